@@ -0,0 +1,57 @@
+/*
+- @Author: aztec
+- @Date: 2026-10-17 00:12:40
+- @Description: 策略基类，提供所有回调的空实现
+- @
+- @Copyright (c) 2024 by aztec, All Rights Reserved.
+*/
+package backtest
+
+import (
+	"time"
+
+	"github.com/aztecqt/dagger/util/datavisual"
+	"github.com/aztecqt/qbench/common"
+	"github.com/aztecqt/qbench/data/bars"
+	"github.com/shopspring/decimal"
+)
+
+// 策略基类
+// 嵌入到具体策略中，策略只需要实现Class、MarketInfoRequired以及自己关心的回调
+type BaseStrategy struct{}
+
+func (BaseStrategy) OnStart(c Context) {}
+
+func (BaseStrategy) OnEnd(c Context) {}
+
+func (BaseStrategy) OnDayChange(day time.Time, c Context) {}
+
+func (BaseStrategy) OnTicker(instId string, t common.Ticker, c Context) {}
+
+func (BaseStrategy) OnDepth(instId string, d common.Depth, c Context) {}
+
+func (BaseStrategy) OnTrade(instId string, t common.Trade, c Context) {}
+
+func (BaseStrategy) OnKlineUnit(instId string, intervalSec int, k common.KlineUnit, c Context) {}
+
+func (BaseStrategy) OnLiquidation(instId string, t common.Trade, c Context) {}
+
+func (BaseStrategy) OnBar(instId string, spec bars.Spec, b bars.Bar, c Context) {}
+
+func (BaseStrategy) OnTimer(id int64, tag string, c Context) {}
+
+func (BaseStrategy) OnFunding(instId string, f common.FundingRate, payment decimal.Decimal, c Context) {
+}
+
+func (BaseStrategy) OnOrderUpdate(o Order, c Context) {}
+
+func (BaseStrategy) OnFill(f Fill, c Context) {}
+
+func (BaseStrategy) OnPositionLiquidated(f Fill, c Context) {}
+
+func (BaseStrategy) OnVisualDataInit(intervalMs int64, c Context) {}
+
+func (BaseStrategy) OnVisualDataRefeshing(dgDefault *VisualGroup, c Context) {}
+
+func (BaseStrategy) OnVisualDataSaving(rootDir string, lcDefault **datavisual.LayoutConfig, c Context) {
+}
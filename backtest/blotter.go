@@ -0,0 +1,82 @@
+/*
+- @Author: aztec
+- @Date: 2026-10-17 01:22:36
+- @Description: 成交簿。记录回测中的每一笔成交，可导出为csv或json lines，用于与实盘成交对账
+- @
+- @Copyright (c) 2024 by aztec, All Rights Reserved.
+*/
+package backtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+
+	"github.com/aztecqt/dagger/util"
+	"github.com/aztecqt/qbench/common"
+)
+
+// 强平单的策略标签
+const OrderTag_Liquidation = "liquidation"
+
+// 成交簿，按成交顺序记录所有成交（包括强平）
+type Blotter struct {
+	Fills []Fill
+}
+
+// 本次运行的成交簿，Run结束后读取
+func (e *Executor) Blotter() *Blotter {
+	return e.blotter
+}
+
+// 设置后续下单使用的策略标签，用于区分同一策略内的不同信号来源。空字符串表示使用策略的Class()
+func (e *Executor) SetOrderTag(tag string) {
+	e.orderTag = tag
+}
+
+func (e *Executor) currentOrderTag() string {
+	if len(e.orderTag) > 0 {
+		return e.orderTag
+	} else if e.strategy != nil {
+		return e.strategy.Class()
+	} else {
+		return ""
+	}
+}
+
+// 导出为csv文件，时间精确到毫秒
+func (b *Blotter) SaveCSV(path string) bool {
+	rows := [][]string{{"time", "order_id", "inst_id", "pos_side", "side", "price", "amount", "taker", "fee", "fee_ccy", "realized_pnl", "strategy_tag"}}
+	for _, fill := range b.Fills {
+		rows = append(rows, []string{
+			fill.Time.Format("2006-01-02 15:04:05.000"),
+			strconv.FormatInt(fill.OrderId, 10),
+			fill.InstId,
+			string(fill.PosSide),
+			util.ValueIf(fill.IsSell, "sell", "buy"),
+			fill.Price.String(),
+			fill.Amount.String(),
+			strconv.FormatBool(fill.Taker),
+			fill.Fee.String(),
+			fill.FeeCcy,
+			fill.RealizedPnl.String(),
+			fill.StrategyTag,
+		})
+	}
+
+	return common.SaveCSV(logPrefix, path, rows)
+}
+
+// 导出为json lines文件，每行一笔成交
+func (b *Blotter) SaveJSONL(path string) bool {
+	buf := bytes.Buffer{}
+	enc := json.NewEncoder(&buf)
+	for _, fill := range b.Fills {
+		if err := enc.Encode(fill); err != nil {
+			common.LogError(logPrefix, "write %s failed: %s", path, err.Error())
+			return false
+		}
+	}
+
+	return common.SaveFile(logPrefix, path, buf.Bytes())
+}
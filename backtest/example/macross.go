@@ -0,0 +1,103 @@
+/*
+- @Author: aztec
+- @Date: 2026-10-17 11:36:20
+- @Description: 示例策略：均线交叉。以匿名导入的方式链接本包即可在任务文件中使用
+- @
+- @Copyright (c) 2024 by aztec, All Rights Reserved.
+*/
+package example
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/aztecqt/qbench/backtest"
+	"github.com/aztecqt/qbench/common"
+	"github.com/shopspring/decimal"
+)
+
+const StrategyName_MaCross = "ma_cross"
+
+func init() {
+	backtest.RegisterStrategy(StrategyName_MaCross, newMaCross)
+}
+
+// 均线交叉策略的参数
+type MaCrossParams struct {
+	InstId      string          `json:"inst_id"`
+	IntervalSec int             `json:"interval_sec"` // k线周期
+	Fast        int             `json:"fast"`         // 快线周期（k线根数）
+	Slow        int             `json:"slow"`         // 慢线周期（k线根数）
+	Amount      decimal.Decimal `json:"amount"`       // 每次开仓数量
+}
+
+// 均线交叉策略
+// 收盘价的快线上穿慢线时以收盘价吃单买入Amount，下穿时卖出Amount平仓。只做多，现货和合约都适用
+type MaCross struct {
+	backtest.BaseStrategy
+	params   MaCrossParams
+	closes   []float64
+	holding  bool
+	lastDiff float64
+}
+
+func newMaCross(params json.RawMessage) (backtest.Strategy, bool) {
+	p := MaCrossParams{IntervalSec: 3600, Fast: 5, Slow: 20}
+	if len(params) > 0 {
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, false
+		}
+	}
+
+	if len(p.InstId) == 0 ||
+		p.Fast <= 0 ||
+		p.Slow <= p.Fast ||
+		!p.Amount.IsPositive() {
+		return nil, false
+	}
+
+	if _, ok := common.Interval2Bar(p.IntervalSec); !ok {
+		return nil, false
+	}
+
+	return &MaCross{params: p}, true
+}
+
+func (s *MaCross) Class() string {
+	return fmt.Sprintf("%s_%d_%d", StrategyName_MaCross, s.params.Fast, s.params.Slow)
+}
+
+func (s *MaCross) MarketInfoRequired() backtest.MarketInfoLoadingConfig {
+	return backtest.MarketInfoLoadingConfig{
+		InstIds: []string{s.params.InstId},
+		Klines:  []backtest.KlineSubscription{{InstId: s.params.InstId, IntervalSec: s.params.IntervalSec}}}
+}
+
+func (s *MaCross) OnKlineUnit(instId string, intervalSec int, k common.KlineUnit, c backtest.Context) {
+	s.closes = append(s.closes, k.ClosePrice.InexactFloat64())
+	if len(s.closes) > s.params.Slow {
+		s.closes = s.closes[1:]
+	}
+
+	if len(s.closes) < s.params.Slow {
+		return
+	}
+
+	diff := average(s.closes[len(s.closes)-s.params.Fast:]) - average(s.closes)
+	if s.lastDiff <= 0 && diff > 0 && !s.holding {
+		c.SignalTaker(instId, k.ClosePrice, s.params.Amount, false)
+		s.holding = true
+	} else if s.lastDiff >= 0 && diff < 0 && s.holding {
+		c.SignalTaker(instId, k.ClosePrice, s.params.Amount, true)
+		s.holding = false
+	}
+	s.lastDiff = diff
+}
+
+func average(values []float64) float64 {
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
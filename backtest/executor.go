@@ -10,7 +10,9 @@ package backtest
 import (
 	"fmt"
 	"maps"
+	"math/rand"
 	"os/exec"
+	"slices"
 	"time"
 
 	"github.com/aztecqt/dagger/util"
@@ -28,34 +30,109 @@ type ExecutorConfig struct {
 	ShowCharts       bool  `json:"show_charts"`
 	ChartsIntervalMs int64 `json:"charts_interval_ms"`
 	chartsInterval   time.Duration
+	ChartsRenderer   string `json:"charts_renderer"` // 可视化数据的展示方式（viewer/html），默认viewer
+	ChartsDir        string `json:"charts_dir"`      // 可视化数据的保存目录，默认为./visual/策略类型/当前时间
+
+	// 不显示回测进度（批量回测时使用）
+	HideProgress bool `json:"hide_progress"`
+
+	// 净值采样周期，用于计算回测结果中的绩效指标
+	NavIntervalMs int64 `json:"nav_interval_ms"`
+	navInterval   time.Duration
 
 	// 手续费率
 	FeeSpotMaker     decimal.Decimal `json:"fee_spot_maker"`
 	FeeSpotTaker     decimal.Decimal `json:"fee_spot_taker"`
 	FeeContractMaker decimal.Decimal `json:"fee_contract_maker"`
 	FeeContractTaker decimal.Decimal `json:"fee_contract_taker"`
+
+	// 挂单成交模型（optimistic/queue），也可以用SetFillModel设置自定义模型
+	FillModel string `json:"fill_model"`
+
+	// 吃单滑点模型（fixed_bps/volatility/sqrt_impact），仅在没有加载真实盘口时生效
+	// 也可以用SetSlippageModel设置自定义模型
+	Slippage          string  `json:"slippage"`
+	SlippageBps       float64 `json:"slippage_bps"`        // fixed_bps模型的滑点（基点）
+	SlippageK         float64 `json:"slippage_k"`          // volatility/sqrt_impact模型的系数
+	SlippageWindowSec int64   `json:"slippage_window_sec"` // 波动率和成交量的统计窗口，默认1小时
+	slippageWindow    time.Duration
+
+	// 延迟模拟
+	// 策略在e.Time发出的交易信号，在e.Time+延迟之后，以该品种的第一个盘口/ticker为准执行
+	// 都为0时不模拟延迟，信号立即执行
+	OrderLatencyMs  int64  `json:"order_latency_ms"`  // 下单延迟
+	FeedLatencyMs   int64  `json:"feed_latency_ms"`   // 行情延迟
+	LatencyJitter   string `json:"latency_jitter"`    // 随机抖动的分布（uniform/normal/exponential），空表示不抖动
+	LatencyJitterMs int64  `json:"latency_jitter_ms"` // 随机抖动的幅度
+	RandSeed        int64  `json:"rand_seed"`         // 随机数种子
+
+	// 合约保证金（逐仓模式）
+	// 杠杆为0表示不计算保证金和强平
+	Leverage              map[string]decimal.Decimal `json:"leverage"`                // 各品种的杠杆倍数，instId->杠杆
+	DefaultLeverage       decimal.Decimal            `json:"default_leverage"`        // 未单独设置时的杠杆倍数
+	MaintenanceMarginRate decimal.Decimal            `json:"maintenance_margin_rate"` // 维持保证金率
+
+	// 使用双向持仓（对冲模式）的合约品种，其余品种为单向持仓
+	HedgeModeInstIds []string `json:"hedge_mode_inst_ids"`
+
+	// 现货余额不足时的处理方式（空/reject/margin），见SpotOversell_XXX
+	// margin模式下，不足的部分自动借入，按MarginInterestRates中各币种的小时利率计息
+	// 默认只借入超卖的基础币种。MarginBorrowQuote为true时，买入超出计价币种余额的部分也自动借入（如借usdt买入）
+	SpotOversell        string                     `json:"spot_oversell"`
+	MarginInterestRates map[string]decimal.Decimal `json:"margin_interest_rates"`
+	MarginBorrowQuote   bool                       `json:"margin_borrow_quote"`
+
+	// 订单检查
+	// 为true时，按本地交易品种规则（instruments/<ex>.json）对齐价格和数量，检查最小下单金额、最大下单数量，
+	// 并检查现货余额和合约保证金，不满足时拒绝订单，通过OnOrderUpdate通知策略
+	ValidateOrders bool `json:"validate_orders"`
+
+	// 估值币种，净值和权益都折算成该币种计算
+	// 为空时：初始资产只有一个币种则用该币种，否则优先usdt，再否则取按字母排序的第一个币种
+	ValuationCcy string `json:"valuation_ccy"`
+
+	// 行情结束时（OnEnd之后）撤销所有挂单和在途订单（尚未执行的撤单、改单指令一并丢弃），并按最新价格吃单平掉所有合约仓位
+	// 现货杠杆账户（SpotOversell=margin）同时买回借入的币种，归还负债
+	FlattenOnEnd bool `json:"flatten_on_end"`
 }
 
 func (e *ExecutorConfig) parse() {
 	e.chartsInterval = time.Millisecond * time.Duration(e.ChartsIntervalMs)
+	if e.NavIntervalMs <= 0 {
+		e.NavIntervalMs = 1000 * 60 * 60
+	}
+	e.navInterval = time.Millisecond * time.Duration(e.NavIntervalMs)
+	if e.SlippageWindowSec <= 0 {
+		e.SlippageWindowSec = 60 * 60
+	}
+	e.slippageWindow = time.Second * time.Duration(e.SlippageWindowSec)
 }
 
 func ExecutorConfigDefault() ExecutorConfig {
 	return ExecutorConfig{
 		ShowCharts:       true,
-		ChartsIntervalMs: 1000 * 60}
+		ChartsIntervalMs: 1000 * 60,
+		ChartsRenderer:   ChartsRenderer_Viewer,
+		NavIntervalMs:    1000 * 60 * 60,
+		FillModel:        FillModel_Optimistic}
 }
 
 type Executor struct {
 	cfg ExecutorConfig
 
-	// 行情序列
-	// 在行情加载阶段，我们把所有类型的行情，按照时间顺序，塌缩到一个一维的时间序列上，比如
+	// 行情流
+	// 每个品种的每种行情都是一个按时间有序的行情源，行情流把它们归并成一个一维的时间序列，比如
 	// [品种A盘口]-[品种A成交]-[品种B成交]-[品种B盘口]-[品种B盘口]...
-	// 这样在运行阶段，只要遍历这个时间序列即可
-	marketInfoSeq                                             []marketInfoUnit
+	// 这样在运行阶段，只要逐个取出行情即可。行情按天惰性加载，不会一次性读入内存
+	stream                                                    *marketInfoStream
 	useTicker, useDepth, useTrades, useLiquidations, useKline bool
+	useFunding                                                bool
 	pxbyTicker, pxbyDepth, pxbyTrades, pxbyKline              bool
+	klineVolumeIntervals                                      map[int]int // 品种索引->用于统计成交量的k线周期
+
+	// bar合成器
+	barBuilders        []*barBuilder
+	barBuildersOfInsts map[int][]int // 品种索引->barBuilders中的索引
 
 	// 行情品种（采用通用instId语法）
 	instIds      []string
@@ -64,17 +141,37 @@ type Executor struct {
 	// 当前时间
 	time.Time
 
+	// 实际回放的第一个、最后一个行情的时间
+	firstTime, lastTime time.Time
+
 	// 初始资产
 	initBalance map[string]decimal.Decimal
 
+	// 估值币种，以及以估值币种计的初始权益
+	// 初始权益在所有初始资产都能折算时才确定（开始时可能还没有价格）
+	valuationCcy    string
+	initEquity      decimal.Decimal
+	initEquityReady bool
+	unvaluedCcys    map[string]bool // 无法折算的币种，只报一次错
+
+	// 汇率图，只跟品种表有关，品种表变化时重建（见priceGraph）
+	valuationGraph map[string][]priceEdge
+
 	// 资产余额 btc->0.1
 	balance map[string]decimal.Decimal
 
 	// 浮动盈亏
 	unrealizedPnl map[string]decimal.Decimal
 
+	// 现货杠杆账户的负债、累计利息，以及下次计息时间
+	liabilities      map[string]decimal.Decimal
+	interest         map[string]decimal.Decimal
+	nextInterestTime time.Time
+
 	// 合约持仓，key=instId
-	positions map[string]*common.ContractPosition
+	// 单向持仓的品种在positions中，双向持仓的品种在dualPositions中
+	positions     map[string]*common.ContractPosition
+	dualPositions map[string]*common.DualSidePosition
 
 	// 各品种当前盘口数据
 	depthOfInsts map[string]common.Depth
@@ -82,22 +179,91 @@ type Executor struct {
 	// 各品种的最新价格
 	priceOfInsts map[string]decimal.Decimal
 
+	// 各品种的挂单，按下单顺序排列
+	openOrders     map[string][]*Order
+	openOrdersById map[int64]*Order
+	nextOrderId    int64
+
+	// 在途订单（已提交，尚未到达交易所）
+	pendingOrders map[int64]*Order
+
+	// 挂单成交模型
+	fillModel FillModel
+
+	// 吃单滑点模型，以及各品种的滚动市场统计
+	slippageModel SlippageModel
+	marketStats   map[string]*marketStatsTracker
+
+	// 交易品种规则，instId->规则
+	rules map[string]common.InstrumentRule
+
+	// 延迟执行的交易指令，按时间排序
+	delayedActions []delayedAction
+	rand           *rand.Rand
+
+	// 各订单最后一个延迟指令的到达时间，保证同一订单的指令不乱序
+	orderActionTimes map[int64]time.Time
+
+	// 待推送给策略的订单事件
+	// 撮合和下单过程中产生的订单更新、成交，先缓存在这里，在策略回调之外统一推送，避免策略回调重入
+	orderEvents []orderEvent
+
+	// 成交簿，及当前下单使用的策略标签
+	blotter  *Blotter
+	orderTag string
+
+	// 当前运行的策略
+	strategy Strategy
+
+	// 定时器，id->定时器。回放结束时间之后的定时器不会触发
+	timers      map[int64]*timer
+	nextTimerId int64
+	endTime     time.Time
+
+	// 回测结果统计
+	navs              []NavPoint
+	breakdowns        []CcyBreakdown
+	navNextSampleTime time.Time
+	instStats         map[string]*InstrumentStats
+	fees              map[string]decimal.Decimal
+
 	// 数据可视化
-	dgDefault         *datavisual.DataGroup
+	dgDefault         *VisualGroup
 	dgNextRefreshTime time.Time
+	visualGroups      []*VisualGroup // 策略通过NewVisualGroup创建的数据组
 }
 
 func NewExecutor(localDataPath string, cfg ExecutorConfig) *Executor {
 	local.Init(localDataPath)
+	return newExecutor(cfg)
+}
+
+func newExecutor(cfg ExecutorConfig) *Executor {
 	cfg.parse()
 	e := &Executor{
-		cfg:           cfg,
-		balance:       map[string]decimal.Decimal{},
-		unrealizedPnl: map[string]decimal.Decimal{},
-		positions:     map[string]*common.ContractPosition{},
-		depthOfInsts:  map[string]common.Depth{},
-		priceOfInsts:  map[string]decimal.Decimal{},
-		dgDefault:     datavisual.NewDataGroup(cfg.ChartsIntervalMs)}
+		cfg:              cfg,
+		balance:          map[string]decimal.Decimal{},
+		unrealizedPnl:    map[string]decimal.Decimal{},
+		liabilities:      map[string]decimal.Decimal{},
+		interest:         map[string]decimal.Decimal{},
+		positions:        map[string]*common.ContractPosition{},
+		dualPositions:    map[string]*common.DualSidePosition{},
+		depthOfInsts:     map[string]common.Depth{},
+		priceOfInsts:     map[string]decimal.Decimal{},
+		openOrders:       map[string][]*Order{},
+		openOrdersById:   map[int64]*Order{},
+		pendingOrders:    map[int64]*Order{},
+		orderActionTimes: map[int64]time.Time{},
+		rand:             rand.New(rand.NewSource(cfg.RandSeed)),
+		fillModel:        newFillModel(cfg.FillModel),
+		slippageModel:    newSlippageModel(cfg),
+		marketStats:      map[string]*marketStatsTracker{},
+		timers:           map[int64]*timer{},
+		instStats:        map[string]*InstrumentStats{},
+		fees:             map[string]decimal.Decimal{},
+		unvaluedCcys:     map[string]bool{},
+		dgDefault:        newVisualGroup(visualGroup_Default, cfg.ChartsIntervalMs),
+		blotter:          &Blotter{}}
 	return e
 }
 
@@ -106,160 +272,303 @@ func (e *Executor) SetBalance(ccy string, amount decimal.Decimal) {
 	e.balance[ccy] = amount
 }
 
+// 设置挂单成交模型（覆盖配置中的选择）
+func (e *Executor) SetFillModel(fm FillModel) {
+	e.fillModel = fm
+}
+
+// 设置吃单滑点模型（覆盖配置中的选择），nil表示不计算滑点
+func (e *Executor) SetSlippageModel(sm SlippageModel) {
+	e.slippageModel = sm
+}
+
 // 执行策略
-// 使用行情驱动策略运行
-func (e *Executor) Run(s strategy, ex common.ExName, t0, t1 time.Time) {
-	// 加载行情
-	e.loadMarketInfo(ex, t0, t1, s.MarketInfoRequired())
+// 使用行情驱动策略运行，返回回测结果
+func (e *Executor) Run(s Strategy, ex common.ExName, t0, t1 time.Time) (*BacktestResult, bool) {
+	// 准备行情
+	if !e.loadMarketInfo(ex, t0, t1, s.MarketInfoRequired()) {
+		return nil, false
+	}
+	e.loadInstrumentRules(ex)
+
+	return e.run(s, t0, t1), true
+}
+
+// 使用预加载的行情执行策略
+// 行情类型以ReplayData加载时的配置为准，不再使用策略的MarketInfoRequired
+func (e *Executor) RunReplay(s Strategy, rd *ReplayData) (*BacktestResult, bool) {
+	if !e.initMarketInfo(rd.cfg) {
+		return nil, false
+	}
+	e.stream.addSource(rd.newSource())
+	if !e.checkMarketInfo() {
+		return nil, false
+	}
+	e.loadInstrumentRules(rd.ex)
+
+	return e.run(s, rd.t0, rd.t1), true
+}
+
+func (e *Executor) run(s Strategy, t0, t1 time.Time) *BacktestResult {
+	e.endTime = t1
+	e.begin(s, t0)
+
+	// 行情是流式读取的，总数未知，因此以回放的时间作为进度
+	var tracker *terminal.TrackerF
+	if !e.cfg.HideProgress {
+		tracker = terminal.GenTrackerWithHardwareInfo("回测", t1.Sub(t0).Seconds(), 30, true, false, true, true, false)
+	}
+
+	for {
+		miu, ok := e.stream.next()
+		if !ok {
+			break
+		}
+
+		if tracker != nil {
+			tracker.SetValue(miu.time.Sub(t0).Seconds())
+		}
+
+		e.step(s, miu)
+	}
+
+	r := e.finish(s)
+
+	if tracker != nil {
+		tracker.MarkAsDone()
+		time.Sleep(time.Millisecond * 100)
+	}
+	return r
+}
 
+// 开始驱动策略之前的准备，t为开始时间
+func (e *Executor) begin(s Strategy, t time.Time) {
 	// 记录初始资产
 	e.initBalance = maps.Clone(e.balance)
+	e.initValuationCcy()
+	e.strategy = s
 
 	// 可视数据初始化
 	if e.cfg.ShowCharts {
 		e.initVisualData(s)
 	}
 
-	tracker := terminal.GenTrackerWithHardwareInfo("回测", float64(len(e.marketInfoSeq)), 30, true, false, true, true, false)
-	for i, miu := range e.marketInfoSeq {
-		tracker.SetValue(float64(i))
-		e.Time = miu.time
-		instId := e.instIds[miu.instIdIndex]
+	// 启动策略
+	e.start(s, t)
+}
 
-		if e.useTicker {
-			if v, ok := miu.data.(common.Ticker); ok {
-				// 刷新当前价格、浮盈
-				if e.pxbyTicker {
-					e.onLatestPrice(instId, v.Price, v.Time)
-				}
+// 处理一个行情单元，并记录起止时间
+func (e *Executor) step(s Strategy, miu marketInfoUnit) {
+	if e.firstTime.IsZero() {
+		e.firstTime = miu.time
+	}
+	e.lastTime = miu.time
 
-				// ticker代替深度
-				if !e.useDepth {
-					e.depthOfInsts[instId] = common.NewDepthFromTicker(v)
-				}
+	e.dispatch(s, miu)
+}
 
-				// 驱动策略
-				s.OnTicker(instId, v, e)
-			}
-		}
+// 行情结束后，结束策略，汇总结果并保存可视化数据
+func (e *Executor) finish(s Strategy) *BacktestResult {
+	// 结束策略
+	e.end(s)
 
-		if e.useDepth {
-			if v, ok := miu.data.(common.Depth); ok {
-				// 刷新深度
-				e.depthOfInsts[instId] = v
+	// 汇总回测结果
+	r := e.buildResult(s)
 
-				// 刷新当前价格、浮盈
-				if e.pxbyDepth {
-					e.onLatestPrice(instId, v.Mid, v.Time)
-				}
+	// 可视化数据保存
+	if e.cfg.ShowCharts {
+		e.saveVisualData(s, r)
+	}
 
-				// 驱动策略
-				s.OnDepth(instId, v, e)
-			}
-		}
+	return r
+}
+
+// 处理一个行情单元或定时器：刷新价格和盘口、执行交易指令、撮合挂单，并驱动策略
+func (e *Executor) dispatch(s Strategy, miu marketInfoUnit) {
+	e.Time = miu.time
+
+	// 杠杆借贷计息
+	e.accrueMarginInterest()
+
+	if te, ok := miu.data.(timerEvent); ok {
+		// 定时器
+		e.fireTimer(s, te)
+	} else if be, ok := miu.data.(barCloseEvent); ok {
+		// 时间bar收盘
+		e.fireBarClose(s, be)
+	} else if _, ok := miu.data.(dayChangeEvent); ok {
+		// 换日
+		e.fireDayChange(s)
+	} else {
+		e.dispatchMarketInfo(s, miu)
+	}
 
-		if e.useTrades || e.useLiquidations {
-			if v, ok := miu.data.(common.Trade); ok {
-				if v.Tag == common.TradeTagNormal {
-					// 刷新当前价格、浮盈
-					if e.pxbyTrades {
-						e.onLatestPrice(instId, v.Price, v.Time)
-					}
+	// 推送策略回调中产生的订单事件
+	e.flushOrderEvents()
 
-					// 驱动策略
-					s.OnTrade(instId, v, e)
-				} else if v.Tag == common.TradeTagLiquidation {
+	// 净值采样
+	e.sampleNav()
 
-					// 驱动策略
-					s.OnLiquidation(instId, v, e)
-				}
+	// 可视化数据刷新
+	if e.cfg.ShowCharts {
+		e.refreshVisualData(s)
+	}
+}
+
+// 处理一个行情单元
+func (e *Executor) dispatchMarketInfo(s Strategy, miu marketInfoUnit) {
+	instId := e.instIds[miu.instIdIndex]
+
+	if e.useTicker {
+		if v, ok := miu.data.(common.Ticker); ok {
+			// 刷新当前价格、浮盈
+			if e.pxbyTicker {
+				e.onLatestPrice(instId, v.Price, v.Time)
+			}
+
+			// ticker代替深度
+			if !e.useDepth {
+				e.depthOfInsts[instId] = common.NewDepthFromTicker(v)
 			}
+
+			// 执行到期的交易指令，撮合挂单
+			e.runDelayedActions(instId)
+			if !e.useDepth {
+				e.matchOrdersByDepth(instId, e.depthOfInsts[instId])
+			}
+			e.flushOrderEvents()
+
+			// 驱动策略
+			s.OnTicker(instId, v, e)
 		}
+	}
 
-		if e.useLiquidations {
-			if v, ok := miu.data.(common.Trade); ok {
-				// 驱动策略
-				s.OnLiquidation(instId, v, e)
+	if e.useDepth {
+		if v, ok := miu.data.(common.Depth); ok {
+			// 刷新深度
+			e.depthOfInsts[instId] = v
+
+			// 刷新当前价格、浮盈
+			if e.pxbyDepth {
+				e.onLatestPrice(instId, v.Mid, v.Time)
 			}
+
+			// 执行到期的交易指令，撮合挂单
+			e.runDelayedActions(instId)
+			e.matchOrdersByDepth(instId, v)
+			e.flushOrderEvents()
+
+			// 驱动策略
+			s.OnDepth(instId, v, e)
 		}
+	}
 
-		if e.useKline {
-			if v, ok := miu.data.(common.KlineUnit); ok {
+	if e.useTrades || e.useLiquidations {
+		if v, ok := miu.data.(common.Trade); ok {
+			if v.Tag == common.TradeTagNormal {
 				// 刷新当前价格、浮盈
-				if e.pxbyKline {
-					e.onLatestPrice(instId, v.ClosePrice, v.Time)
+				if e.pxbyTrades {
+					e.onLatestPrice(instId, v.Price, v.Time)
 				}
 
+				// 统计成交量
+				e.onMarketVolume(instId, v.Time, v.Size)
+
+				// 没有盘口时，交易指令以成交为准执行
+				if !e.useDepth && !e.useTicker {
+					e.runDelayedActions(instId)
+				}
+
+				// 撮合挂单
+				e.matchOrdersByTrade(instId, v)
+				e.flushOrderEvents()
+
 				// 驱动策略
-				s.OnKlineUnit(instId, v, e)
-			}
-		}
+				s.OnTrade(instId, v, e)
 
-		// 可视化数据刷新
-		if e.cfg.ShowCharts {
-			e.refreshVisualData(s)
+				// 合成bar
+				e.onTradeForBars(s, miu.instIdIndex, v)
+			} else if v.Tag == common.TradeTagLiquidation {
+				// 驱动策略
+				s.OnLiquidation(instId, v, e)
+			}
 		}
 	}
 
-	// 可视化数据保存
-	if e.cfg.ShowCharts {
-		e.saveVisualData(s)
-	}
-
-	tracker.MarkAsDone()
-	time.Sleep(time.Millisecond * 100)
-}
+	if e.useFunding {
+		if v, ok := miu.data.(common.FundingRate); ok {
+			// 结算资金费
+			payment := e.settleFunding(instId, v)
 
-// 计算当前单位净值
-// 目前仅能计算单一初始币种的情况
-// 将当前所有资产，折算成目标资产数量，然后计算单位净值
-func (e *Executor) nav() decimal.Decimal {
-	if len(e.initBalance) >= 1 {
-		// 初始资产类型和数量
-		baseCcy := ""
-		baseBal := decimal.Zero
-		for k, d := range e.initBalance {
-			baseCcy = k
-			baseBal = d
-			break
+			// 驱动策略
+			s.OnFunding(instId, v, payment, e)
 		}
+	}
 
-		if baseBal.IsZero() {
-			return util.DecimalOne
-		}
+	if e.useKline {
+		if v, ok := miu.data.(klineEvent); ok {
+			// 刷新当前价格、浮盈
+			// 默认按收盘时间推送，推送时刻与收盘价对应；KlineAtOpenTime时推送时刻为开盘时间（旧行为）
+			if e.pxbyKline {
+				e.onLatestPrice(instId, v.k.ClosePrice, miu.time)
+			}
 
-		// 将所有资产折算成初始资产数量
-		currBal := decimal.Zero
-		for ccy, amount := range e.balance {
-			currBal = currBal.Add(e.exchangeToCcy(ccy, baseCcy, amount))
-		}
+			// 没有逐笔成交时，用k线统计成交量
+			if !e.useTrades && e.klineVolumeIntervals[miu.instIdIndex] == v.intervalSec {
+				e.onMarketVolume(instId, miu.time, v.k.Volume)
+			}
 
-		for ccy, amount := range e.unrealizedPnl {
-			currBal = currBal.Add(e.exchangeToCcy(ccy, baseCcy, amount))
-		}
+			// 没有盘口和成交时，交易指令以k线为准执行
+			if !e.useDepth && !e.useTicker && !e.useTrades {
+				e.runDelayedActions(instId)
+			}
+			e.flushOrderEvents()
 
-		return currBal.Div(baseBal)
-	} else {
-		return util.DecimalOne
+			// 驱动策略
+			s.OnKlineUnit(instId, v.intervalSec, v.k, e)
+		}
 	}
 }
 
-func (e *Executor) exchangeToCcy(srcCcy, dstCcy string, amount decimal.Decimal) decimal.Decimal {
-	if srcCcy == dstCcy {
-		return amount
+// 执行一笔成交，修改仓位和资产
+// 返回成交记录（不含订单id）。双向持仓平仓数量超过仓位时，成交数量会被剪裁
+func (e *Executor) execute(instId string, side common.PosSide, price, amount decimal.Decimal, isSell, taker bool) Fill {
+	f := Fill{InstId: instId, PosSide: side, Time: e.Time, Price: price, Amount: amount, IsSell: isSell, Taker: taker}
+	if !amount.IsPositive() {
+		return f
 	}
 
-	if px, ok := e.priceOfInsts[fmt.Sprintf("%s_%s", srcCcy, dstCcy)]; ok {
-		return amount.Mul(px)
-	} else if px, ok := e.priceOfInsts[fmt.Sprintf("%s_%s", dstCcy, srcCcy)]; ok {
-		return amount.Div(px)
+	if common.GetInstType(instId) == common.InstType_Spot {
+		baseCcy, quoteCcy := common.InstId2Ccys(instId)
+		if isSell {
+			amount = e.clipSpotSell(baseCcy, amount)
+			f.Amount = amount
+			if !amount.IsPositive() {
+				return f
+			}
+			f.Fee, f.FeeCcy = e.spotSell(baseCcy, quoteCcy, price, amount, taker)
+		} else {
+			f.Fee, f.FeeCcy = e.spotBuy(baseCcy, quoteCcy, price, amount, taker)
+		}
+
+		// 杠杆账户借款/还款
+		// 超卖的基础币种自动借入；计价币种不足时，只有开启MarginBorrowQuote才借入
+		e.settleSpotMargin(baseCcy, true)
+		e.settleSpotMargin(quoteCcy, e.cfg.MarginBorrowQuote)
+	} else {
+		if isSell {
+			amount = amount.Neg()
+		}
+		dealt := decimal.Zero
+		f.Fee, f.FeeCcy, dealt, f.RealizedPnl = e.contractDeal(instId, side, price, amount, taker)
+		f.Amount = dealt.Abs()
 	}
 
-	return decimal.Zero
+	return f
 }
 
-// 模拟现货买入
-func (e *Executor) spotBuy(baseCcy, quoteCcy string, price, amount decimal.Decimal, taker bool) {
+// 模拟现货买入，手续费以baseCcy支付
+func (e *Executor) spotBuy(baseCcy, quoteCcy string, price, amount decimal.Decimal, taker bool) (decimal.Decimal, string) {
 	// 修改资产数量
 	quoteAmount := price.Mul(amount)
 	fee := amount.Mul(util.ValueIf(taker, e.cfg.FeeSpotTaker, e.cfg.FeeSpotMaker))
@@ -269,11 +578,12 @@ func (e *Executor) spotBuy(baseCcy, quoteCcy string, price, amount decimal.Decim
 
 	// 记录成交
 	instId := fmt.Sprintf("%s_%s", baseCcy, quoteCcy)
-	e.dgDefault.RecordPoint(instId, datavisual.Point{Time: e.Time, Value: price.InexactFloat64(), Tag: datavisual.PointTag_Buy})
+	e.recordDealPoint(instId, price, false)
+	return fee, baseCcy
 }
 
-// 模拟现货卖出
-func (e *Executor) spotSell(baseCcy, quoteCcy string, price, amount decimal.Decimal, taker bool) {
+// 模拟现货卖出，手续费以quoteCcy支付
+func (e *Executor) spotSell(baseCcy, quoteCcy string, price, amount decimal.Decimal, taker bool) (decimal.Decimal, string) {
 	// 修改资产数量
 	quoteAmount := price.Mul(amount)
 	fee := quoteAmount.Mul(util.ValueIf(taker, e.cfg.FeeSpotTaker, e.cfg.FeeSpotMaker))
@@ -283,60 +593,163 @@ func (e *Executor) spotSell(baseCcy, quoteCcy string, price, amount decimal.Deci
 
 	// 记录成交
 	instId := fmt.Sprintf("%s_%s", baseCcy, quoteCcy)
-	e.dgDefault.RecordPoint(instId, datavisual.Point{Time: e.Time, Value: price.InexactFloat64(), Tag: datavisual.PointTag_Sell})
+	e.recordDealPoint(instId, price, true)
+	return fee, quoteCcy
 }
 
 // 模拟合约交易。amount正数表示买入，负数表示卖出
-func (e *Executor) contractDeal(instId string, price, amount decimal.Decimal, taker bool) {
-	// 找出持仓对象
-	if _, ok := e.positions[instId]; !ok {
-		ct := common.NewContractPosition(
-			e.cfg.FeeContractMaker,
-			e.cfg.FeeContractTaker,
-			common.IsUsdtContract(instId),
-			false,
-			common.InstId2MarginCcy(instId))
-		e.positions[instId] = ct
-	}
+// 手续费以保证金币种支付
+// 返回手续费、手续费币种、实际成交数量（带符号）、平仓盈亏
+func (e *Executor) contractDeal(instId string, side common.PosSide, price, amount decimal.Decimal, taker bool) (decimal.Decimal, string, decimal.Decimal, decimal.Decimal) {
+	marginCcy := common.InstId2MarginCcy(instId)
+	fee := decimal.Zero
+	profit := decimal.Zero
+	if e.isHedgeMode(instId) {
+		// 找出持仓对象
+		if _, ok := e.dualPositions[instId]; !ok {
+			dp := common.NewDualSidePosition(
+				e.cfg.FeeContractMaker,
+				e.cfg.FeeContractTaker,
+				common.IsUsdtContract(instId),
+				false,
+				marginCcy)
+			dp.SetMargin(e.leverageOf(instId), e.cfg.MaintenanceMarginRate)
+			e.dualPositions[instId] = dp
+		}
+
+		// 模拟交易
+		fee, profit, amount = e.dualPositions[instId].Deal(side, price, amount, taker, e.Time, nil)
+		if amount.IsZero() {
+			return decimal.Zero, marginCcy, decimal.Zero, decimal.Zero
+		}
+	} else {
+		// 找出持仓对象
+		if _, ok := e.positions[instId]; !ok {
+			ct := common.NewContractPosition(
+				e.cfg.FeeContractMaker,
+				e.cfg.FeeContractTaker,
+				common.IsUsdtContract(instId),
+				false,
+				marginCcy)
+			ct.SetMargin(e.leverageOf(instId), e.cfg.MaintenanceMarginRate)
+			e.positions[instId] = ct
+		}
 
-	// 模拟交易
-	ct := e.positions[instId]
-	fee, profit := ct.Deal(price, amount, taker, e.Time, nil)
+		// 模拟交易
+		fee, profit = e.positions[instId].Deal(price, amount, taker, e.Time, nil)
+	}
 
-	// 修改余额（手续费）
-	e.balance[ct.MarginCcy] = e.balance[ct.MarginCcy].Add(profit.Sub(fee))
+	// 修改余额（手续费），有盈余时归还该币种的现货杠杆负债
+	e.balance[marginCcy] = e.balance[marginCcy].Add(profit.Sub(fee))
+	e.settleSpotMargin(marginCcy, false)
 
 	// 记录成交
-	e.dgDefault.RecordPoint(
-		instId,
-		datavisual.Point{
-			Time:  e.Time,
-			Value: price.InexactFloat64(),
-			Tag: util.ValueIf(
-				amount.IsPositive(),
-				datavisual.PointTag_Buy,
-				datavisual.PointTag_Sell)})
+	e.recordDealPoint(instId, price, amount.IsNegative())
+	return fee, marginCcy, amount, profit
+}
+
+// 是否为双向持仓的品种
+func (e *Executor) isHedgeMode(instId string) bool {
+	return slices.Contains(e.cfg.HedgeModeInstIds, instId)
+}
+
+// 查询某品种某一侧的仓位对象
+// 单向持仓品种只接受PosSide_Net，双向持仓品种只接受PosSide_Long/PosSide_Short
+func (e *Executor) positionOf(instId string, side common.PosSide) (*common.ContractPosition, bool) {
+	if dp, ok := e.dualPositions[instId]; ok {
+		return dp.Side(side)
+	} else if pos, ok := e.positions[instId]; ok && side == common.PosSide_Net {
+		return pos, true
+	} else {
+		return nil, false
+	}
+}
+
+// 遍历某品种的所有仓位对象
+func (e *Executor) forEachPositionOf(instId string, fn func(side common.PosSide, pos *common.ContractPosition)) {
+	if dp, ok := e.dualPositions[instId]; ok {
+		fn(common.PosSide_Long, dp.Long)
+		fn(common.PosSide_Short, dp.Short)
+	} else if pos, ok := e.positions[instId]; ok {
+		fn(common.PosSide_Net, pos)
+	}
+}
+
+// 遍历所有仓位对象
+func (e *Executor) forEachPosition(fn func(instId string, side common.PosSide, pos *common.ContractPosition)) {
+	for instId, pos := range e.positions {
+		fn(instId, common.PosSide_Net, pos)
+	}
+
+	for instId, dp := range e.dualPositions {
+		fn(instId, common.PosSide_Long, dp.Long)
+		fn(instId, common.PosSide_Short, dp.Short)
+	}
+}
+
+// 按最新价格结算资金费，计入保证金币种余额
+// 返回本次资金费，正数表示收入
+func (e *Executor) settleFunding(instId string, fr common.FundingRate) decimal.Decimal {
+	px, ok := e.priceOfInsts[instId]
+	payment := decimal.Zero
+	e.forEachPositionOf(instId, func(side common.PosSide, pos *common.ContractPosition) {
+		if pos.Position.IsZero() {
+			return
+		}
+
+		if !ok {
+			common.LogError(logPrefix, "no price for %s at funding time %s, skip funding", instId, fr.Time.Format(time.DateTime))
+			return
+		}
+
+		p := pos.Funding(fr.Rate, px)
+		e.balance[pos.MarginCcy] = e.balance[pos.MarginCcy].Add(p)
+		e.settleSpotMargin(pos.MarginCcy, false)
+		payment = payment.Add(p)
+	})
+
+	return payment
 }
 
 // 刷新最新价格
 func (e *Executor) onLatestPrice(instId string, price decimal.Decimal, time time.Time) {
 	// 刷新价格
 	e.priceOfInsts[instId] = price
+	if ms := e.marketStatsOf(instId); ms != nil {
+		ms.onPrice(time, price)
+	}
 
 	// 刷新浮盈
-	if pos, ok := e.positions[instId]; ok {
+	marginCcy := ""
+	e.forEachPositionOf(instId, func(side common.PosSide, pos *common.ContractPosition) {
 		pos.Update(price)
-		e.unrealizedPnl[pos.MarginCcy] = pos.UnRealizedProfit
+		e.checkLiquidation(instId, side, pos, price)
+		marginCcy = pos.MarginCcy
+	})
+
+	if len(marginCcy) > 0 {
+		e.refreshUnrealizedPnl(marginCcy)
 	}
 }
 
+// 重新汇总某保证金币种下所有仓位的浮盈
+func (e *Executor) refreshUnrealizedPnl(marginCcy string) {
+	pnl := decimal.Zero
+	e.forEachPosition(func(instId string, side common.PosSide, pos *common.ContractPosition) {
+		if pos.MarginCcy == marginCcy {
+			pnl = pnl.Add(pos.UnRealizedProfit)
+		}
+	})
+	e.unrealizedPnl[marginCcy] = pnl
+}
+
 // 可视化数据初始化
-func (e *Executor) initVisualData(s strategy) {
+func (e *Executor) initVisualData(s Strategy) {
 	s.OnVisualDataInit(e.cfg.ChartsIntervalMs, e)
 }
 
 // 可视化数据刷新
-func (e *Executor) refreshVisualData(s strategy) {
+func (e *Executor) refreshVisualData(s Strategy) {
 	if e.Time.After(e.dgNextRefreshTime) {
 		// strategy层面处理
 		s.OnVisualDataRefeshing(e.dgDefault, e)
@@ -347,30 +760,47 @@ func (e *Executor) refreshVisualData(s strategy) {
 }
 
 // 生成可视化数据
-func (e *Executor) saveVisualData(s strategy) {
+func (e *Executor) saveVisualData(s Strategy, r *BacktestResult) {
 	var lcDefault *datavisual.LayoutConfig
 
 	// 生成extraInfo
-	t0 := e.marketInfoSeq[0].time
-	t1 := e.marketInfoSeq[len(e.marketInfoSeq)-1].time
+	t0 := e.firstTime
+	t1 := e.lastTime
 	e.dgDefault.SaveExtraInfo(fmt.Sprintf("起始时间：%s\r\n", t0.Format(time.DateTime)))
 	e.dgDefault.SaveExtraInfo(fmt.Sprintf("结束时间：%s\r\n", t1.Format(time.DateTime)))
 	e.dgDefault.SaveExtraInfo(fmt.Sprintf("总时长：%s\r\n", util.Duration2Str(t1.Sub(t0))))
-	e.dgDefault.SaveExtraInfo(fmt.Sprintf("单位净值：%.4f\r\n", e.nav().InexactFloat64()))
+	e.dgDefault.SaveExtraInfo(fmt.Sprintf("单位净值：%.4f（%s）\r\n", e.nav().InexactFloat64(), e.valuationCcy))
+	e.dgDefault.SaveExtraInfo(fmt.Sprintf("年化收益：%.2f%%\r\n", r.AnnualReturn*100))
+	e.dgDefault.SaveExtraInfo(fmt.Sprintf("夏普比率：%.2f\r\n", r.Sharpe))
+	e.dgDefault.SaveExtraInfo(fmt.Sprintf("最大回撤：%.2f%%\r\n", r.MaxDrawdown*100))
 
 	// 数据保存目录
-	rootDir := fmt.Sprintf("./visual/%s/%s", s.Class(), time.Now().Format("2006-01-02.15-04-05"))
+	rootDir := e.cfg.ChartsDir
+	if len(rootDir) == 0 {
+		rootDir = fmt.Sprintf("./visual/%s/%s", s.Class(), time.Now().Format("2006-01-02.15-04-05"))
+	}
 
 	// strategy层面处理
 	s.OnVisualDataSaving(rootDir, &lcDefault, e)
 
 	// 存储可视化数据，并展示
-	defaultDgDir := fmt.Sprintf("%s/default", rootDir)
+	defaultDgDir := fmt.Sprintf("%s/%s", rootDir, visualGroup_Default)
 	e.dgDefault.SaveToDir(defaultDgDir)
-	lcDefault.SaveToDir(defaultDgDir)
+	if lcDefault != nil {
+		lcDefault.SaveToDir(defaultDgDir)
+	}
+	for _, g := range e.visualGroups {
+		g.SaveToDir(fmt.Sprintf("%s/%s", rootDir, g.name))
+	}
 	datavisual.GenerateLayoutGroupConfig(rootDir)
-	cmd := exec.Command("CommonDataViewer.exe", rootDir)
-	go cmd.Run()
+
+	// 展示
+	if e.useHtmlRenderer() {
+		e.saveHtmlReport(s, rootDir, r)
+	} else {
+		cmd := exec.Command("CommonDataViewer.exe", rootDir)
+		go cmd.Run()
+	}
 }
 
 // 生成颜色表（ccy-color + instId-color)
@@ -0,0 +1,98 @@
+/*
+- @Author: aztec
+- @Date: 2026-10-16 22:48:20
+- @Description: executor的bar订阅部分。用逐笔成交实时合成bar并推送给策略
+- @
+- @Copyright (c) 2024 by aztec, All Rights Reserved.
+*/
+package backtest
+
+import (
+	"github.com/aztecqt/qbench/common"
+	"github.com/aztecqt/qbench/data/bars"
+)
+
+// 一个bar订阅
+type BarSubscription struct {
+	InstId string
+	Spec   bars.Spec
+}
+
+// 一个品种的一个bar合成器
+type barBuilder struct {
+	instIdIndex int
+	spec        bars.Spec
+	builder     bars.Builder
+	scheduled   bool // 当前时间bar的收盘事件是否已插入行情流
+}
+
+// 时间bar收盘事件，作为一个行情单元插入行情流
+// 收盘时刻之后可能很久没有成交，需要靠这个事件及时结束bar
+type barCloseEvent struct {
+	index int // barBuilders中的索引
+}
+
+// 根据订阅创建bar合成器
+func (e *Executor) initBars(cfg MarketInfoLoadingConfig) bool {
+	e.barBuilders = nil
+	e.barBuildersOfInsts = map[int][]int{}
+	if len(cfg.Bars) == 0 {
+		return true
+	}
+
+	if !cfg.Trades {
+		common.LogError(logPrefix, "bar subscriptions require trades")
+		return false
+	}
+
+	for _, sub := range cfg.Bars {
+		index, ok := e.instIdIndexs[sub.InstId]
+		if !ok {
+			common.LogError(logPrefix, "bar subscription %s is not in InstIds", sub.InstId)
+			return false
+		}
+
+		b, ok := bars.NewBuilder(sub.Spec)
+		if !ok {
+			common.LogError(logPrefix, "invalid bar subscription %s for %s", sub.Spec, sub.InstId)
+			return false
+		}
+
+		e.barBuildersOfInsts[index] = append(e.barBuildersOfInsts[index], len(e.barBuilders))
+		e.barBuilders = append(e.barBuilders, &barBuilder{instIdIndex: index, spec: sub.Spec, builder: b})
+	}
+
+	return true
+}
+
+// 用一笔成交驱动该品种的所有bar合成器
+func (e *Executor) onTradeForBars(s Strategy, instIdIndex int, t common.Trade) {
+	for _, index := range e.barBuildersOfInsts[instIdIndex] {
+		bb := e.barBuilders[index]
+		if bar, ok := bb.builder.Add(t); ok {
+			bb.scheduled = false
+			e.flushOrderEvents()
+			s.OnBar(e.instIds[bb.instIdIndex], bb.spec, bar, e)
+		}
+
+		// 新开的时间bar，在收盘时刻插入收盘事件。超过回放结束时间的不再插入
+		if bb.spec.Type == bars.BarType_Time && !bb.scheduled {
+			if cur, ok := bb.builder.Current(); ok {
+				bb.scheduled = true
+				if e.endTime.IsZero() || !cur.CloseTime.After(e.endTime) {
+					e.stream.push(marketInfoUnit{instIdIndex: bb.instIdIndex, time: cur.CloseTime, data: barCloseEvent{index: index}})
+				}
+			}
+		}
+	}
+}
+
+// 时间bar到达收盘时刻
+// 收盘时刻恰好有成交时，这根bar已经被成交结束了，这里不会重复推送
+func (e *Executor) fireBarClose(s Strategy, be barCloseEvent) {
+	bb := e.barBuilders[be.index]
+	if bar, ok := bb.builder.CloseAt(e.Time); ok {
+		bb.scheduled = false
+		s.OnBar(e.instIds[bb.instIdIndex], bb.spec, bar, e)
+	}
+}
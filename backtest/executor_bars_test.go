@@ -0,0 +1,70 @@
+package backtest
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/aztecqt/qbench/data/bars"
+)
+
+func TestExecutorBars(t *testing.T) {
+	const instId = "btc_usdt_swap"
+	minute := bars.Spec{Type: bars.BarType_Time, IntervalMs: 60000}
+
+	cases := []struct {
+		name   string
+		spec   bars.Spec
+		trades [][3]float64 // 时间（秒）、价格、数量
+		endSec float64
+		expect []string // 开盘-收盘时间 成交量@推送时间
+	}{
+		{"time bar closed without later trade", minute, [][3]float64{
+			{0, 100, 1}, {20, 102, 2},
+		}, 300, []string{"0-60 v3@60"}},
+		{"time bar after a gap", minute, [][3]float64{
+			{0, 100, 1}, {130, 101, 1},
+		}, 300, []string{"0-60 v1@60", "120-180 v1@180"}},
+		{"trade at close time is not pushed twice", minute, [][3]float64{
+			{0, 100, 1}, {60, 101, 2},
+		}, 300, []string{"0-60 v1@60", "60-120 v2@120"}},
+		{"time bar closing after end", minute, [][3]float64{
+			{0, 100, 1},
+		}, 50, []string{}},
+		{"volume bar closed by trade", bars.Spec{Type: bars.BarType_Volume, Threshold: 3}, [][3]float64{
+			{0, 100, 1}, {20, 102, 2}, {40, 101, 1},
+		}, 300, []string{"0-20 v3@20"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			m := newTestMarket(MarketInfoLoadingConfig{
+				InstIds: []string{instId},
+				Trades:  true,
+				Bars:    []BarSubscription{{InstId: instId, Spec: c.spec}}})
+			for _, tr := range c.trades {
+				m.trade(instId, tr[0], tr[1], tr[2], 'b')
+			}
+			m.t1 = testTime(c.endSec)
+
+			got := []string{}
+			s := &testStrategy{}
+			s.onBar = func(_ string, spec bars.Spec, b bars.Bar, ctx Context) {
+				if spec != c.spec {
+					t.Errorf("unexpected spec %v", spec)
+				}
+				got = append(got, fmt.Sprintf("%v-%v v%v@%v",
+					b.OpenTime.Sub(testT0).Seconds(),
+					b.CloseTime.Sub(testT0).Seconds(),
+					b.Volume,
+					ctx.GetTime().Sub(testT0).Seconds()))
+			}
+
+			m.run(t, ExecutorConfigDefault(), s, map[string]float64{"usdt": 10000})
+
+			if !slices.Equal(got, c.expect) {
+				t.Errorf("expect %v, got %v", c.expect, got)
+			}
+		})
+	}
+}
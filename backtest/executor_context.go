@@ -29,10 +29,19 @@ func (e *Executor) GetBalance(ccy string) (decimal.Decimal, bool) {
 	}
 }
 
+// 现货杠杆账户的负债（借款+利息）
+func (e *Executor) GetLiability(ccy string) decimal.Decimal {
+	return e.liabilities[ccy]
+}
+
+// 双向持仓的品种，返回多空合计的净仓位，均价为0
 func (e *Executor) GetPosition(instId string) (amount decimal.Decimal, avgPrice decimal.Decimal) {
 	if pos, ok := e.positions[instId]; ok {
 		amount = pos.Position
 		avgPrice = pos.PositionAvgPriceOpen
+	} else if dp, ok := e.dualPositions[instId]; ok {
+		amount = dp.NetPosition()
+		avgPrice = decimal.Zero
 	} else {
 		amount = decimal.Zero
 		avgPrice = decimal.Zero
@@ -40,6 +49,29 @@ func (e *Executor) GetPosition(instId string) (amount decimal.Decimal, avgPrice
 	return
 }
 
+func (e *Executor) GetPositionOfSide(instId string, side common.PosSide) (amount decimal.Decimal, avgPrice decimal.Decimal) {
+	if pos, ok := e.positionOf(instId, side); ok {
+		amount = pos.Position
+		avgPrice = pos.PositionAvgPriceOpen
+	} else {
+		amount = decimal.Zero
+		avgPrice = decimal.Zero
+	}
+	return
+}
+
+func (e *Executor) GetLiquidationPrice(instId string) (decimal.Decimal, bool) {
+	return e.GetLiquidationPriceOfSide(instId, common.PosSide_Net)
+}
+
+func (e *Executor) GetLiquidationPriceOfSide(instId string, side common.PosSide) (decimal.Decimal, bool) {
+	if pos, ok := e.positionOf(instId, side); ok {
+		return pos.LiquidationPrice()
+	} else {
+		return decimal.Zero, false
+	}
+}
+
 func (e *Executor) GetLatestPrice(instId string) (decimal.Decimal, bool) {
 	if v, ok := e.priceOfInsts[instId]; ok {
 		return v, true
@@ -56,31 +88,88 @@ func (e *Executor) GetDepth(instId string) (common.Depth, bool) {
 	}
 }
 
-func (e *Executor) SignalTaker(instId string, price, amount decimal.Decimal, isSell bool) {
-	// 如果有盘口数据，先按照盘口深度，计算出最大交易量，对amount进行剪裁，然后计算真实成交价格和真实成交数量
-	// 如果没有盘口数据，则跳过这一步
-	if v, ok := e.depthOfInsts[instId]; ok {
-		maxAmount := v.GetMaxAmount(price, isSell)
-		if maxAmount.LessThan(amount) {
-			amount = maxAmount
-		}
+func (e *Executor) GetOpenOrders(instId string) []Order {
+	return e.getOpenOrders(instId)
+}
+
+func (e *Executor) SetLeverage(instId string, leverage decimal.Decimal) {
+	e.setLeverage(instId, leverage)
+}
+
+func (e *Executor) SignalTaker(instId string, price, amount decimal.Decimal, isSell bool) int64 {
+	return e.SignalTakerOfSide(instId, common.PosSide_Net, price, amount, isSell)
+}
+
+func (e *Executor) SignalMaker(instId string, price, amount decimal.Decimal, isSell bool) int64 {
+	return e.SignalMakerOfSide(instId, common.PosSide_Net, price, amount, isSell)
+}
+
+func (e *Executor) SignalTakerOfSide(instId string, side common.PosSide, price, amount decimal.Decimal, isSell bool) int64 {
+	if !e.checkPosSide(instId, side) {
+		return 0
+	}
+
+	o := e.newOrder(instId, side, price, amount, isSell, true)
+	e.submit(o, func() { e.takeOrder(o) })
+	return o.Id
+}
 
-		// 根据数量，反算成交价格
-		price, amount = v.GetAvgPrice(amount, isSell)
+func (e *Executor) SignalMakerOfSide(instId string, side common.PosSide, price, amount decimal.Decimal, isSell bool) int64 {
+	if !e.checkPosSide(instId, side) {
+		return 0
 	}
 
-	// 执行交易
+	o := e.newOrder(instId, side, price, amount, isSell, false)
+	e.submit(o, func() { e.placeOrder(o) })
+	return o.Id
+}
+
+// 检查持仓方向与品种的持仓模式是否匹配
+func (e *Executor) checkPosSide(instId string, side common.PosSide) bool {
 	if common.GetInstType(instId) == common.InstType_Spot {
-		baseCcy, quoteCcy := common.InstId2Ccys(instId)
-		if isSell {
-			e.spotSell(baseCcy, quoteCcy, price, amount, true)
-		} else {
-			e.spotBuy(baseCcy, quoteCcy, price, amount, true)
+		if side != common.PosSide_Net {
+			common.LogError(logPrefix, "spot %s does not support position side %s", instId, side)
+			return false
 		}
-	} else {
-		if isSell {
-			amount = amount.Neg()
+	} else if e.isHedgeMode(instId) {
+		if side != common.PosSide_Long && side != common.PosSide_Short {
+			common.LogError(logPrefix, "%s is in hedge mode, position side must be long or short", instId)
+			return false
 		}
-		e.contractDeal(instId, price, amount, true)
+	} else if side != common.PosSide_Net {
+		common.LogError(logPrefix, "%s is not in hedge mode, position side %s not allowed", instId, side)
+		return false
 	}
+
+	return true
+}
+
+func (e *Executor) SetTimer(at time.Time, tag string) int64 {
+	return e.setTimer(at, tag)
+}
+
+func (e *Executor) SetInterval(interval time.Duration, tag string) int64 {
+	return e.setInterval(interval, tag)
+}
+
+func (e *Executor) CancelTimer(id int64) bool {
+	return e.cancelTimer(id)
+}
+
+func (e *Executor) CancelOrder(id int64) bool {
+	if !e.isOrderKnown(id) {
+		return false
+	}
+
+	e.delay(id, e.instIdOfOrder(id), func() { e.cancelOrder(id) })
+	return true
+}
+
+func (e *Executor) AmendOrder(id int64, price, amount decimal.Decimal) bool {
+	if !e.isOrderKnown(id) {
+		return false
+	}
+
+	e.delay(id, e.instIdOfOrder(id), func() { e.amendOrder(id, price, amount) })
+	return true
 }
@@ -0,0 +1,80 @@
+/*
+- @Author: aztec
+- @Date: 2026-10-16 16:41:09
+- @Description: executor的html报告部分
+- @
+- @Copyright (c) 2024 by aztec, All Rights Reserved.
+*/
+package backtest
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/aztecqt/dagger/util"
+	"github.com/aztecqt/qbench/backtest/htmlreport"
+	"github.com/aztecqt/qbench/common"
+	"github.com/shopspring/decimal"
+)
+
+// 可视化数据的展示方式，用于ExecutorConfig
+const (
+	ChartsRenderer_Viewer = "viewer" // 保存datavisual数据，并启动CommonDataViewer.exe查看
+	ChartsRenderer_Html   = "html"   // 保存datavisual数据，并生成一个自包含的html报告，不启动任何程序
+)
+
+func (e *Executor) useHtmlRenderer() bool {
+	return e.cfg.ChartsRenderer == ChartsRenderer_Html
+}
+
+// 记录一个成交点
+func (e *Executor) recordDealPoint(instId string, price decimal.Decimal, isSell bool) {
+	e.dgDefault.RecordMarker(instId, e.Time, price.InexactFloat64(), isSell)
+}
+
+// 生成html报告
+func (e *Executor) saveHtmlReport(s Strategy, rootDir string, r *BacktestResult) {
+	rpt := htmlreport.NewReport(fmt.Sprintf("%s 回测报告", s.Class()))
+	rpt.AddInfo("起始时间：%s", r.StartTime.Format(time.DateTime))
+	rpt.AddInfo("结束时间：%s", r.EndTime.Format(time.DateTime))
+	rpt.AddInfo("总时长：%s", util.Duration2Str(r.EndTime.Sub(r.StartTime)))
+	rpt.AddInfo("单位净值：%.4f（%s）", e.nav().InexactFloat64(), r.ValuationCcy)
+	rpt.AddInfo("年化收益：%.2f%%  年化波动：%.2f%%", r.AnnualReturn*100, r.AnnualVolatility*100)
+	rpt.AddInfo("夏普比率：%.2f  索提诺比率：%.2f  卡玛比率：%.2f", r.Sharpe, r.Sortino, r.Calmar)
+	rpt.AddInfo("最大回撤：%.2f%%  最长回撤时间：%s", r.MaxDrawdown*100, util.Duration2Str(time.Duration(r.MaxDrawdownDurationSec)*time.Second))
+	for ccy, interest := range r.Interest {
+		rpt.AddInfo("%s借贷利息：%.6f", ccy, interest)
+	}
+	rpt.AddInfo("平仓次数：%d  胜率：%.2f%%", r.ClosedTrades, r.WinRate*100)
+	ccys := []string{}
+	for ccy := range r.AvgWin {
+		ccys = append(ccys, ccy)
+	}
+	for ccy := range r.AvgLoss {
+		if _, ok := r.AvgWin[ccy]; !ok {
+			ccys = append(ccys, ccy)
+		}
+	}
+	slices.Sort(ccys)
+	for _, ccy := range ccys {
+		rpt.AddInfo("%s平均盈利：%.4f  平均亏损：%.4f", ccy, r.AvgWin[ccy], r.AvgLoss[ccy])
+	}
+
+	// 净值曲线来自回测结果，其余图表来自各可视化数据组
+	navs := make([]htmlreport.Point, 0, len(r.Navs))
+	for _, p := range r.Navs {
+		navs = append(navs, htmlreport.Point{Time: p.Time, Value: p.Nav})
+	}
+	rpt.AddChart("单位净值").AddLine("nav", navs)
+
+	e.dgDefault.addToReport(rpt, true)
+	for _, g := range e.visualGroups {
+		g.addToReport(rpt, false)
+	}
+
+	path := fmt.Sprintf("%s/report.html", rootDir)
+	if rpt.SaveToFile(path) {
+		common.LogNormal(logPrefix, "html report saved to %s", path)
+	}
+}
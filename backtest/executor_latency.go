@@ -0,0 +1,110 @@
+/*
+- @Author: aztec
+- @Date: 2026-10-16 10:41:17
+- @Description: executor的延迟模拟部分
+- @
+- @Copyright (c) 2024 by aztec, All Rights Reserved.
+*/
+package backtest
+
+import (
+	"math"
+	"slices"
+	"time"
+)
+
+// 延迟抖动的分布类型，用于ExecutorConfig
+const (
+	LatencyJitter_None        = ""
+	LatencyJitter_Uniform     = "uniform"     // [0, jitter)均匀分布
+	LatencyJitter_Normal      = "normal"      // 标准差为jitter的半正态分布
+	LatencyJitter_Exponential = "exponential" // 均值为jitter的指数分布
+)
+
+// 延迟执行的交易指令（下单/撤单/改单）
+type delayedAction struct {
+	time    time.Time
+	orderId int64
+	instId  string
+	fn      func()
+}
+
+// 是否模拟延迟
+func (e *Executor) latencyEnabled() bool {
+	return e.cfg.OrderLatencyMs > 0 ||
+		e.cfg.FeedLatencyMs > 0 ||
+		e.cfg.LatencyJitter != LatencyJitter_None && e.cfg.LatencyJitterMs > 0
+}
+
+// 采样一次信号延迟
+// 策略看到的行情本身就晚了FeedLatency，发出的指令又要经过OrderLatency才能到达交易所
+// 对于撮合来说，两者等效于指令在行情时间之后FeedLatency+OrderLatency才到达
+func (e *Executor) sampleLatency() time.Duration {
+	ms := float64(e.cfg.FeedLatencyMs + e.cfg.OrderLatencyMs)
+	jitter := float64(e.cfg.LatencyJitterMs)
+	switch e.cfg.LatencyJitter {
+	case LatencyJitter_Uniform:
+		ms += e.rand.Float64() * jitter
+	case LatencyJitter_Normal:
+		ms += math.Abs(e.rand.NormFloat64()) * jitter
+	case LatencyJitter_Exponential:
+		ms += e.rand.ExpFloat64() * jitter
+	}
+
+	return time.Duration(ms * float64(time.Millisecond))
+}
+
+// 延迟执行某个订单的交易指令
+// 不模拟延迟时立即执行
+// 同一订单的指令按提交顺序到达：到达时间不早于该订单上一个指令的到达时间，避免撤单因为抖动跑到下单前面
+func (e *Executor) delay(orderId int64, instId string, fn func()) {
+	if !e.latencyEnabled() {
+		fn()
+		return
+	}
+
+	t := e.Time.Add(e.sampleLatency())
+	if last, ok := e.orderActionTimes[orderId]; ok && last.After(t) {
+		t = last
+	}
+	e.orderActionTimes[orderId] = t
+
+	da := delayedAction{time: t, orderId: orderId, instId: instId, fn: fn}
+
+	// 按时间顺序插入，同一时间的指令保持提交顺序
+	i, _ := slices.BinarySearchFunc(e.delayedActions, da.time, func(a delayedAction, t time.Time) int {
+		if a.time.After(t) {
+			return 1
+		} else {
+			return -1
+		}
+	})
+	e.delayedActions = slices.Insert(e.delayedActions, i, da)
+}
+
+// 执行某品种已经到期的交易指令
+// 在该品种的盘口/ticker刷新之后调用，这样指令总是以到达之后的第一个盘口为准执行
+func (e *Executor) runDelayedActions(instId string) {
+	if len(e.delayedActions) == 0 {
+		return
+	}
+
+	due := []delayedAction{}
+	n := 0
+	for _, da := range e.delayedActions {
+		if da.instId == instId && !da.time.After(e.Time) {
+			due = append(due, da)
+		} else {
+			e.delayedActions[n] = da
+			n++
+		}
+	}
+	e.delayedActions = e.delayedActions[:n]
+
+	for _, da := range due {
+		if e.orderActionTimes[da.orderId].Equal(da.time) {
+			delete(e.orderActionTimes, da.orderId)
+		}
+		da.fn()
+	}
+}
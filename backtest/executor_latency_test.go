@@ -0,0 +1,94 @@
+package backtest
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/aztecqt/qbench/common"
+	"github.com/aztecqt/qbench/internal/testutil"
+)
+
+func TestExecutorLatency(t *testing.T) {
+	const instId = "btc_usdt_swap"
+
+	cases := []struct {
+		name      string
+		orderMs   int64
+		feedMs    int64
+		expectSec float64
+		expectPx  float64
+	}{
+		{"no latency", 0, 0, 0, 101},
+		{"arrives with a depth", 500, 0, 0.5, 102},
+		{"first depth after arrival", 1000, 0, 1.5, 103},
+		{"feed latency adds up", 400, 600, 1.5, 103},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			m := newTestMarket(MarketInfoLoadingConfig{InstIds: []string{instId}, Depth: true}).
+				depth(instId, 0, [][2]float64{{100, 10}}, [][2]float64{{101, 10}}).
+				depth(instId, 0.5, [][2]float64{{101, 10}}, [][2]float64{{102, 10}}).
+				depth(instId, 1.5, [][2]float64{{102, 10}}, [][2]float64{{103, 10}})
+
+			s := &testStrategy{}
+			s.onDepth = func(_ string, d common.Depth, ctx Context) {
+				if d.Time.Equal(testTime(0)) {
+					ctx.SignalTaker(instId, testutil.Dec(110), testutil.Dec(1), false)
+				}
+			}
+
+			cfg := ExecutorConfigDefault()
+			cfg.OrderLatencyMs = c.orderMs
+			cfg.FeedLatencyMs = c.feedMs
+			m.run(t, cfg, s, map[string]float64{"usdt": 10000})
+
+			expect := []testFill{{1, c.expectSec, c.expectPx, 1, true}}
+			if got := s.fillSummary(); !slices.Equal(got, expect) {
+				t.Errorf("expect fills %+v, got %+v", expect, got)
+			}
+		})
+	}
+}
+
+func TestExecutorLatencyJitterKeepsOrder(t *testing.T) {
+	const instId = "btc_usdt_swap"
+
+	for _, jitter := range []string{LatencyJitter_Uniform, LatencyJitter_Normal, LatencyJitter_Exponential} {
+		t.Run(jitter, func(t *testing.T) {
+			// 每100ms一个盘口，足够让所有指令到达
+			m := newTestMarket(MarketInfoLoadingConfig{InstIds: []string{instId}, Depth: true})
+			for i := 0; i <= 100; i++ {
+				m.depth(instId, float64(i)/10, [][2]float64{{99, 10}}, [][2]float64{{101, 10}})
+			}
+
+			for seed := int64(1); seed <= 20; seed++ {
+				// 下单后立即撤单，撤单不能因为抖动先于下单到达
+				s := &testStrategy{}
+				s.onDepth = func(_ string, d common.Depth, ctx Context) {
+					if d.Time.Equal(testTime(0)) {
+						id := ctx.SignalMaker(instId, testutil.Dec(98), testutil.Dec(1), false)
+						ctx.CancelOrder(id)
+					}
+				}
+
+				cfg := ExecutorConfigDefault()
+				cfg.OrderLatencyMs = 100
+				cfg.LatencyJitter = jitter
+				cfg.LatencyJitterMs = 2000
+				cfg.RandSeed = seed
+				m.run(t, cfg, s, map[string]float64{"usdt": 10000})
+
+				statuses := []OrderStatus{}
+				for _, o := range s.updates {
+					statuses = append(statuses, o.Status)
+				}
+
+				expect := []OrderStatus{OrderStatus_Open, OrderStatus_Cancelled}
+				if !slices.Equal(statuses, expect) {
+					t.Errorf("seed %d: expect order updates %v, got %v", seed, expect, statuses)
+				}
+			}
+		})
+	}
+}
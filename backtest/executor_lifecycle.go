@@ -0,0 +1,198 @@
+/*
+- @Author: aztec
+- @Date: 2026-10-17 01:40:12
+- @Description: executor的策略生命周期部分：开始、换日、结束（可选自动平仓）
+- @
+- @Copyright (c) 2024 by aztec, All Rights Reserved.
+*/
+package backtest
+
+import (
+	"slices"
+	"time"
+
+	"github.com/aztecqt/dagger/util"
+	"github.com/aztecqt/qbench/common"
+	"github.com/shopspring/decimal"
+)
+
+// 自动平仓单的策略标签
+const OrderTag_Flatten = "flatten"
+
+// 换日事件，作为一个行情单元插入行情流
+type dayChangeEvent struct{}
+
+// 行情准备完毕后，启动策略
+func (e *Executor) start(s Strategy, t time.Time) {
+	e.Time = t
+	e.scheduleDayChange()
+	s.OnStart(e)
+	e.flushOrderEvents()
+}
+
+// 在下一个零点插入换日事件，超过回放结束时间的不再插入
+func (e *Executor) scheduleDayChange() {
+	next := util.DateOfTime(e.Time).AddDate(0, 0, 1)
+	if !e.endTime.IsZero() && next.After(e.endTime) {
+		return
+	}
+
+	e.stream.push(marketInfoUnit{instIdIndex: -1, time: next, data: dayChangeEvent{}})
+}
+
+func (e *Executor) fireDayChange(s Strategy) {
+	e.scheduleDayChange()
+	s.OnDayChange(util.DateOfTime(e.Time), e)
+}
+
+// 行情结束后、统计结果前，结束策略
+// 此时策略下的单不会再被执行（没有后续行情），需要平仓时使用FlattenOnEnd
+func (e *Executor) end(s Strategy) {
+	s.OnEnd(e)
+	e.flushOrderEvents()
+
+	if e.cfg.FlattenOnEnd {
+		e.flattenPositions()
+		e.flushOrderEvents()
+	}
+}
+
+// 撤销所有挂单，并以吃单方式立即平掉所有合约仓位（不经过订单延迟）
+// 现货杠杆账户还会买回借入的币种，归还负债
+func (e *Executor) flattenPositions() {
+	e.dropPendingOrders()
+
+	ids := make([]int64, 0, len(e.openOrdersById))
+	for id := range e.openOrdersById {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	for _, id := range ids {
+		e.cancelOrder(id)
+	}
+
+	for _, instId := range e.instIds {
+		e.forEachPositionOf(instId, func(side common.PosSide, pos *common.ContractPosition) {
+			if pos.Position.IsZero() {
+				return
+			}
+
+			e.flattenTake(instId, side, pos.Position.IsPositive(), func() decimal.Decimal { return pos.Position.Abs() })
+		})
+	}
+
+	if e.spotMarginEnabled() {
+		e.repayLiabilities()
+	}
+}
+
+// 丢弃尚未到达交易所的在途订单，以及所有尚未执行的撤单、改单指令
+// 在途订单以撤销状态通知策略，避免它们在平仓之后才成交，或残留在最终状态中
+func (e *Executor) dropPendingOrders() {
+	e.delayedActions = nil
+	clear(e.orderActionTimes)
+
+	ids := make([]int64, 0, len(e.pendingOrders))
+	for id := range e.pendingOrders {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+
+	for _, id := range ids {
+		o := e.pendingOrders[id]
+		delete(e.pendingOrders, id)
+		o.Status = OrderStatus_Cancelled
+		o.UpdateTime = e.Time
+		e.pushOrderUpdate(o)
+	}
+}
+
+// 以吃单方式成交fnRemaining()的数量，fnRemaining返回还需要成交的数量
+// 盘口深度不足或订单被拒绝时，剩余部分按最新价格直接成交，保证结束时没有残留
+func (e *Executor) flattenTake(instId string, side common.PosSide, isSell bool, fnRemaining func() decimal.Decimal) {
+	price, ok := e.flattenPrice(instId, isSell)
+	if !ok {
+		common.LogError(logPrefix, "flatten %s failed: no price", instId)
+		return
+	}
+
+	o := e.newOrder(instId, side, price, fnRemaining(), isSell, true)
+	o.Tag = OrderTag_Flatten
+	e.takeOrder(o)
+
+	rest := fnRemaining()
+	if !rest.IsPositive() {
+		return
+	}
+
+	price, ok = e.GetLatestPrice(instId)
+	if !ok {
+		common.LogError(logPrefix, "flatten %s failed: %v left and no price", instId, rest)
+		return
+	}
+
+	common.LogNormal(logPrefix, "flatten %s: %v left after taking the book, filled at last price %v", instId, rest, price)
+	o = e.newOrder(instId, side, price, rest, isSell, true)
+	o.Tag = OrderTag_Flatten
+	f := e.execute(instId, side, price, rest, isSell, true)
+	o.Filled = f.Amount
+	e.pushFill(o, f)
+	o.Status = util.ValueIf(o.Remaining().IsPositive(), OrderStatus_Cancelled, OrderStatus_Filled)
+	o.UpdateTime = e.Time
+	e.pushOrderUpdate(o)
+}
+
+// 归还现货杠杆账户的负债
+// 先用余额归还，不足的部分通过现货品种买回（手续费以买入币种支付，买入数量按费率放大并向上取整）
+// 没有对应现货品种的币种（如借入的计价币种）只能用余额归还，仍未还清的记录错误日志
+func (e *Executor) repayLiabilities() {
+	ccys := []string{}
+	for ccy, liab := range e.liabilities {
+		if liab.IsPositive() {
+			ccys = append(ccys, ccy)
+		}
+	}
+	slices.Sort(ccys)
+
+	for _, ccy := range ccys {
+		e.settleSpotMargin(ccy, false)
+		if !e.liabilities[ccy].IsPositive() {
+			continue
+		}
+
+		for _, instId := range e.instIds {
+			if common.GetInstType(instId) != common.InstType_Spot {
+				continue
+			}
+
+			if baseCcy, _ := common.InstId2Ccys(instId); baseCcy == ccy {
+				netRate := util.DecimalOne.Sub(e.cfg.FeeSpotTaker)
+				e.flattenTake(instId, common.PosSide_Net, false, func() decimal.Decimal {
+					// 向上取整，避免除法的舍入误差留下极小的负债
+					return e.liabilities[ccy].Div(netRate).RoundUp(12)
+				})
+				break
+			}
+		}
+	}
+
+	for _, ccy := range ccys {
+		e.settleSpotMargin(ccy, false)
+		if liab := e.liabilities[ccy]; liab.IsPositive() {
+			common.LogError(logPrefix, "liability of %s not repaid on flatten: %v", ccy, liab)
+		}
+	}
+}
+
+// 平仓价格。有盘口时取对手方最差一档，保证能吃掉整个盘口；否则取最新价格
+func (e *Executor) flattenPrice(instId string, isSell bool) (decimal.Decimal, bool) {
+	if d, ok := e.depthOfInsts[instId]; ok {
+		if isSell && len(d.Bids) > 0 {
+			return d.Bids[len(d.Bids)-1].Price, true
+		} else if !isSell && len(d.Asks) > 0 {
+			return d.Asks[len(d.Asks)-1].Price, true
+		}
+	}
+
+	return e.GetLatestPrice(instId)
+}
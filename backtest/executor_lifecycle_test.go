@@ -0,0 +1,88 @@
+package backtest
+
+import (
+	"testing"
+
+	"github.com/aztecqt/qbench/common"
+	"github.com/aztecqt/qbench/internal/testutil"
+)
+
+func TestExecutorFlattenOnEnd(t *testing.T) {
+	const instId = "btc_usdt_swap"
+
+	cases := []struct {
+		name          string
+		flatten       bool
+		expectPos     float64
+		expectPending int                   // 残留的在途订单
+		expectDelayed int                   // 残留的延迟指令
+		expectStatus  map[int64]OrderStatus // 订单id->最后推送的状态，Pending表示从未推送
+	}{
+		{"keep state", false, 1, 2, 3, map[int64]OrderStatus{
+			1: OrderStatus_Filled,
+			2: OrderStatus_Open,
+			3: OrderStatus_Pending,
+			4: OrderStatus_Pending,
+		}},
+		{"flatten", true, 0, 0, 0, map[int64]OrderStatus{
+			1: OrderStatus_Filled,
+			2: OrderStatus_Cancelled,
+			3: OrderStatus_Cancelled,
+			4: OrderStatus_Cancelled,
+		}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			// 下单延迟1秒，最后一个盘口在2.5秒，2秒时发出的指令都来不及执行
+			book := func(m *testMarket, sec float64) *testMarket {
+				return m.depth(instId, sec, [][2]float64{{100, 10}}, [][2]float64{{101, 10}})
+			}
+			m := newTestMarket(MarketInfoLoadingConfig{InstIds: []string{instId}, Depth: true})
+			book(book(book(m, 0), 2), 2.5)
+
+			s := &testStrategy{}
+			s.onDepth = func(_ string, d common.Depth, ctx Context) {
+				switch {
+				case d.Time.Equal(testTime(0)):
+					ctx.SignalTaker(instId, testutil.Dec(101), testutil.Dec(1), false) // 1：开仓
+					ctx.SignalMaker(instId, testutil.Dec(99), testutil.Dec(1), false)  // 2：挂单
+				case d.Time.Equal(testTime(2)):
+					ctx.AmendOrder(2, testutil.Dec(99.5), testutil.Dec(2))
+					ctx.SignalMaker(instId, testutil.Dec(102), testutil.Dec(1), true)  // 3：在途挂单
+					ctx.SignalTaker(instId, testutil.Dec(101), testutil.Dec(5), false) // 4：在途吃单
+				}
+			}
+
+			cfg := ExecutorConfigDefault()
+			cfg.OrderLatencyMs = 1000
+			cfg.FlattenOnEnd = c.flatten
+			e, _ := m.run(t, cfg, s, map[string]float64{"usdt": 10000})
+
+			if pos, _ := e.GetPosition(instId); !pos.Equal(testutil.Dec(c.expectPos)) {
+				t.Errorf("expect position %v, got %v", c.expectPos, pos)
+			}
+
+			if len(e.pendingOrders) != c.expectPending || len(e.delayedActions) != c.expectDelayed {
+				t.Errorf("expect %d pending orders and %d delayed actions, got %d and %d",
+					c.expectPending, c.expectDelayed, len(e.pendingOrders), len(e.delayedActions))
+			}
+
+			if c.flatten && len(e.GetOpenOrders(instId)) > 0 {
+				t.Errorf("residual open orders after flatten: %+v", e.GetOpenOrders(instId))
+			}
+
+			for id, status := range c.expectStatus {
+				o, ok := s.lastUpdate(id)
+				if !ok && status != OrderStatus_Pending || ok && o.Status != status {
+					t.Errorf("order %d: expect %v, got %v (notified=%v)", id, status, o.Status, ok)
+				}
+			}
+
+			// 未执行的改单不生效
+			if o, ok := s.lastUpdate(2); ok && !o.Price.Equal(testutil.Dec(99)) {
+				t.Errorf("amend should not take effect, got price %v", o.Price)
+			}
+		})
+	}
+}
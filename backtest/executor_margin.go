@@ -0,0 +1,72 @@
+/*
+- @Author: aztec
+- @Date: 2026-10-16 11:38:26
+- @Description: executor的合约保证金与强平部分
+- @
+- @Copyright (c) 2024 by aztec, All Rights Reserved.
+*/
+package backtest
+
+import (
+	"slices"
+
+	"github.com/aztecqt/qbench/common"
+	"github.com/shopspring/decimal"
+)
+
+// 查询某品种的杠杆倍数
+// 优先使用单独设置的杠杆，其次使用默认杠杆。0表示不计算保证金和强平
+func (e *Executor) leverageOf(instId string) decimal.Decimal {
+	if lev, ok := e.cfg.Leverage[instId]; ok {
+		return lev
+	} else {
+		return e.cfg.DefaultLeverage
+	}
+}
+
+// 设置某品种的杠杆倍数，对已有仓位立即生效
+func (e *Executor) setLeverage(instId string, leverage decimal.Decimal) {
+	if e.cfg.Leverage == nil {
+		e.cfg.Leverage = map[string]decimal.Decimal{}
+	}
+	e.cfg.Leverage[instId] = leverage
+
+	if pos, ok := e.positions[instId]; ok {
+		pos.SetMargin(leverage, e.cfg.MaintenanceMarginRate)
+	}
+
+	if dp, ok := e.dualPositions[instId]; ok {
+		dp.SetMargin(leverage, e.cfg.MaintenanceMarginRate)
+	}
+}
+
+// 检查仓位是否触发强平，触发时按标记价格强制平仓
+// 强平前先撤销该品种同一持仓方向的所有挂单
+func (e *Executor) checkLiquidation(instId string, side common.PosSide, pos *common.ContractPosition, markPrice decimal.Decimal) {
+	if !pos.ShouldLiquidate(markPrice) {
+		return
+	}
+
+	liqPx, _ := pos.LiquidationPrice()
+	common.LogNormal(
+		logPrefix,
+		"%s position %v liquidated at %v (liquidation price %v)",
+		instId, pos.Position, markPrice, liqPx)
+
+	for _, o := range slices.Clone(e.openOrders[instId]) {
+		if o.PosSide == side {
+			e.cancelOrder(o.Id)
+		}
+	}
+
+	o := e.newOrder(instId, side, markPrice, pos.Position.Abs(), pos.Position.IsPositive(), true)
+	o.Tag = OrderTag_Liquidation
+	f := e.execute(instId, side, markPrice, o.Amount, o.IsSell, true)
+	f.OrderId = o.Id
+	f.StrategyTag = o.Tag
+	o.Filled = o.Amount
+	o.Status = OrderStatus_Filled
+	e.pushFill(o, f)
+	e.pushOrderUpdate(o)
+	e.orderEvents = append(e.orderEvents, orderEvent{liquidation: &f})
+}
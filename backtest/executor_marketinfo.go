@@ -12,7 +12,6 @@ import (
 	"time"
 
 	"github.com/aztecqt/dagger/util"
-	"github.com/aztecqt/dagger/util/terminal"
 	"github.com/aztecqt/qbench/common"
 	"github.com/aztecqt/qbench/data/local"
 )
@@ -24,73 +23,117 @@ type MarketInfoLoadingConfig struct {
 	Depth            bool
 	Trades           bool
 	Liquidations     bool
-	KlineIntervalSec int
+	FundingRates     bool // 仅对永续合约有效
+	KlineIntervalSec int  // 所有品种使用同一周期的k线
+
+	// k线订阅列表，可以为不同品种订阅不同周期，同一品种也可以订阅多个周期
+	// 订阅的品种必须包含在InstIds中
+	Klines []KlineSubscription
+
+	// k线默认在收盘时刻（开盘时间+周期）推送并参与排序，此时k线的收盘价、高低点都已确定，不会用到未来数据
+	// 设为true则按开盘时间推送（旧行为，仅用于兼容。与盘口、成交等行情混用时存在未来数据问题）
+	KlineAtOpenTime bool
+
+	// 用逐笔成交实时合成的bar（时间、tick、成交量、成交额bar），通过OnBar推送
+	// 需要同时加载Trades
+	Bars []BarSubscription
+}
+
+// 一个k线订阅
+type KlineSubscription struct {
+	InstId      string
+	IntervalSec int
+}
+
+// 所有k线订阅。KlineIntervalSec展开为所有品种的订阅，重复的订阅只保留一个
+func (cfg MarketInfoLoadingConfig) klineSubscriptions() []KlineSubscription {
+	subs := []KlineSubscription{}
+	if cfg.KlineIntervalSec > 0 {
+		for _, instId := range cfg.InstIds {
+			subs = append(subs, KlineSubscription{InstId: instId, IntervalSec: cfg.KlineIntervalSec})
+		}
+	}
+
+	for _, sub := range cfg.Klines {
+		if !slices.Contains(subs, sub) {
+			subs = append(subs, sub)
+		}
+	}
+
+	return subs
 }
 
-// 加载指定品种的、指定时间段内的、指定类型行情
-// klineIntervalSec填0表示不需要k线
+// k线行情单元，附带k线周期
+type klineEvent struct {
+	intervalSec int
+	k           common.KlineUnit
+}
+
+// 准备指定品种的、指定时间段内的、指定类型行情
+// KlineIntervalSec填0且Klines为空表示不需要k线
+// 这里只检查数据是否齐全，并为每个品种的每种行情建立行情源，数据在回测运行过程中按天惰性加载
 func (e *Executor) loadMarketInfo(
 	ex common.ExName,
 	t0, t1 time.Time,
 	cfg MarketInfoLoadingConfig,
 ) bool {
-	e.instIds = cfg.InstIds
-	e.instIdIndexs = map[string]int{}
-	for i, v := range cfg.InstIds {
-		e.instIdIndexs[v] = i
+	if !e.initMarketInfo(cfg) {
+		return false
 	}
 
-	// 估算加载进度：
-	// 加载1个文件算1分(加载1个depth算3分)，sort算1分
-	prgMax :=
-		1 + float64(len(cfg.InstIds))*
-			(util.ValueIf(cfg.Ticker, 1.0, 0)+
-				util.ValueIf(cfg.Depth, 3.0, 0)+
-				util.ValueIf(cfg.Trades, 1.0, 0)+
-				util.ValueIf(cfg.Liquidations, 1.0, 0)+
-				util.ValueIf(cfg.KlineIntervalSec > 0, 1.0, 0))
-
-	tracker := terminal.GenTrackerWithHardwareInfo("行情加载", prgMax, 30, true, false, true, true, true)
-
-	// 加载数据
-	if cfg.Ticker {
-		if !e.loadTickers(t0, t1, ex, tracker) {
-			tracker.MarkAsErrored()
-			return false
-		}
-		e.useTicker = true
+	// 建立行情源
+	if cfg.Ticker && !e.loadTickers(t0, t1, ex) {
+		return false
 	}
 
-	if cfg.Depth {
-		if !e.loadDepths(t0, t1, ex, tracker) {
-			tracker.MarkAsErrored()
-			return false
-		}
-		e.useDepth = true
+	if cfg.Depth && !e.loadDepths(t0, t1, ex) {
+		return false
 	}
 
-	if cfg.Trades {
-		if !e.loadTrades(t0, t1, ex, tracker) {
-			tracker.MarkAsErrored()
-			return false
-		}
-		e.useTrades = true
+	if cfg.Trades && !e.loadTrades(t0, t1, ex) {
+		return false
 	}
 
-	if cfg.Liquidations {
-		if !e.loadLiquidations(t0, t1, ex, tracker) {
-			tracker.MarkAsErrored()
-			return false
-		}
-		e.useLiquidations = true
+	if cfg.Liquidations && !e.loadLiquidations(t0, t1, ex) {
+		return false
 	}
 
-	if cfg.KlineIntervalSec > 0 {
-		if !e.loadKlines(t0, t1, ex, cfg.KlineIntervalSec, tracker) {
-			tracker.MarkAsErrored()
-			return false
+	if cfg.FundingRates && !e.loadFundingRates(t0, t1, ex) {
+		return false
+	}
+
+	if subs := cfg.klineSubscriptions(); len(subs) > 0 && !e.loadKlines(t0, t1, ex, subs, !cfg.KlineAtOpenTime) {
+		return false
+	}
+
+	return e.checkMarketInfo()
+}
+
+// 根据行情配置，初始化品种表、行情类型标记、bar合成器和空的行情流
+func (e *Executor) initMarketInfo(cfg MarketInfoLoadingConfig) bool {
+	e.instIds = cfg.InstIds
+	e.valuationGraph = nil
+	e.instIdIndexs = map[string]int{}
+	for i, v := range cfg.InstIds {
+		e.instIdIndexs[v] = i
+	}
+	e.stream = newMarketInfoStream()
+
+	e.useTicker = cfg.Ticker
+	e.useDepth = cfg.Depth
+	e.useTrades = cfg.Trades
+	e.useLiquidations = cfg.Liquidations
+	e.useFunding = cfg.FundingRates
+	e.useKline = cfg.KlineIntervalSec > 0 || len(cfg.Klines) > 0
+
+	// 同一品种订阅了多个周期时，只用最小周期的k线统计成交量
+	e.klineVolumeIntervals = map[int]int{}
+	for _, sub := range cfg.klineSubscriptions() {
+		if index, ok := e.instIdIndexs[sub.InstId]; ok {
+			if v, ok := e.klineVolumeIntervals[index]; !ok || sub.IntervalSec < v {
+				e.klineVolumeIntervals[index] = sub.IntervalSec
+			}
 		}
-		e.useKline = cfg.KlineIntervalSec > 0
 	}
 
 	if e.useKline {
@@ -103,25 +146,21 @@ func (e *Executor) loadMarketInfo(
 		e.pxbyDepth = true
 	}
 
-	// 初始化可视数据起始时间
-	if len(e.marketInfoSeq) > 0 {
-		e.dgNextRefreshTime = util.AlignTime(e.marketInfoSeq[0].time, e.cfg.ChartsIntervalMs)
+	return e.initBars(cfg)
+}
+
+// 检查行情流是否有数据，并初始化可视数据起始时间
+func (e *Executor) checkMarketInfo() bool {
+	if t, ok := e.stream.peekTime(); ok {
+		e.dgNextRefreshTime = util.AlignTime(t, e.cfg.ChartsIntervalMs)
+		return true
 	} else {
 		common.LogError(logPrefix, "no data loaded!")
 		return false
 	}
-
-	// 数据加载完毕，执行排序
-	slices.SortFunc(e.marketInfoSeq, func(a, b marketInfoUnit) int {
-		return a.time.Compare(b.time)
-	})
-	time.Sleep(time.Millisecond * 100)
-
-	tracker.MarkAsDone()
-	return true
 }
 
-func (e *Executor) loadTickers(t0, t1 time.Time, exName common.ExName, tracker *terminal.TrackerF) bool {
+func (e *Executor) loadTickers(t0, t1 time.Time, exName common.ExName) bool {
 	validInstIds := local.GetValidTickerInstIds(exName)
 	for _, instId := range e.instIds {
 		if slices.Contains(validInstIds, instId) {
@@ -140,20 +179,17 @@ func (e *Executor) loadTickers(t0, t1 time.Time, exName common.ExName, tracker *
 		}
 	}
 
-	for instId, index := range e.instIdIndexs {
-		tickers := local.LoadTickers(t0, t1, exName, instId, func(i, n int) {
-			tracker.Increment(1.0 / float64(n))
-		})
-		for _, t := range tickers {
-			m := marketInfoUnit{instIdIndex: index, time: t.Time, data: t}
-			e.marketInfoSeq = append(e.marketInfoSeq, m)
-		}
+	for index, instId := range e.instIds {
+		it := local.IterTickers(t0, t1, exName, instId)
+		e.stream.addSource(newIterSource(it, index, func(t common.Ticker) (time.Time, interface{}) {
+			return t.Time, t
+		}))
 	}
 
 	return true
 }
 
-func (e *Executor) loadDepths(t0, t1 time.Time, exName common.ExName, tracker *terminal.TrackerF) bool {
+func (e *Executor) loadDepths(t0, t1 time.Time, exName common.ExName) bool {
 	validInstIds := local.GetValidDepthInstIds(exName)
 	for _, instId := range e.instIds {
 		if slices.Contains(validInstIds, instId) {
@@ -172,20 +208,17 @@ func (e *Executor) loadDepths(t0, t1 time.Time, exName common.ExName, tracker *t
 		}
 	}
 
-	for instId, index := range e.instIdIndexs {
-		depths := local.LoadDepth(t0, t1, exName, instId, func(i, n int) {
-			tracker.Increment(3.0 / float64(n))
-		})
-		for _, d := range depths {
-			m := marketInfoUnit{instIdIndex: index, time: d.Time, data: d}
-			e.marketInfoSeq = append(e.marketInfoSeq, m)
-		}
+	for index, instId := range e.instIds {
+		it := local.IterDepth(t0, t1, exName, instId)
+		e.stream.addSource(newIterSource(it, index, func(d common.Depth) (time.Time, interface{}) {
+			return d.Time, d
+		}))
 	}
 
 	return true
 }
 
-func (e *Executor) loadTrades(t0, t1 time.Time, exName common.ExName, tracker *terminal.TrackerF) bool {
+func (e *Executor) loadTrades(t0, t1 time.Time, exName common.ExName) bool {
 	validInstIds := local.GetValidTradesInstIds(exName)
 	for _, instId := range e.instIds {
 		if slices.Contains(validInstIds, instId) {
@@ -204,67 +237,111 @@ func (e *Executor) loadTrades(t0, t1 time.Time, exName common.ExName, tracker *t
 		}
 	}
 
-	for instId, index := range e.instIdIndexs {
-		trades := local.LoadTrades(t0, t1, exName, instId, func(i, n int) {
-			tracker.Increment(1.0 / float64(n))
-		})
-		for _, t := range trades {
+	for index, instId := range e.instIds {
+		it := local.IterTrades(t0, t1, exName, instId)
+		e.stream.addSource(newIterSource(it, index, func(t common.Trade) (time.Time, interface{}) {
 			t.Tag = common.TradeTagNormal
-			m := marketInfoUnit{instIdIndex: index, time: t.Time, data: t}
-			e.marketInfoSeq = append(e.marketInfoSeq, m)
-		}
+			return t.Time, t
+		}))
 	}
 
 	return true
 }
 
 // 跟trade不同，有可能某些日期没有对应的爆仓数据，因此不做检查，仅做加载
-func (e *Executor) loadLiquidations(t0, t1 time.Time, exName common.ExName, tracker *terminal.TrackerF) bool {
-	for instId, index := range e.instIdIndexs {
-		trades := local.LoadLiquidation(t0, t1, exName, instId, func(i, n int) {
-			tracker.Increment(1.0 / float64(n))
-		})
-		for _, t := range trades {
+func (e *Executor) loadLiquidations(t0, t1 time.Time, exName common.ExName) bool {
+	for index, instId := range e.instIds {
+		it := local.IterLiquidation(t0, t1, exName, instId)
+		e.stream.addSource(newIterSource(it, index, func(t common.Trade) (time.Time, interface{}) {
 			t.Tag = common.TradeTagLiquidation
-			m := marketInfoUnit{instIdIndex: index, time: t.Time, data: t}
-			e.marketInfoSeq = append(e.marketInfoSeq, m)
+			return t.Time, t
+		}))
+	}
+
+	return true
+}
+
+// 资金费率只针对永续合约加载，现货品种跳过
+func (e *Executor) loadFundingRates(t0, t1 time.Time, exName common.ExName) bool {
+	validInstIds := local.GetValidFundingInstIds(exName)
+	for _, instId := range e.instIds {
+		if common.GetInstType(instId) == common.InstType_Spot {
+			continue
+		}
+
+		if slices.Contains(validInstIds, instId) {
+			if tmin, tmax, ok := local.GetValidFundingTimeRange(exName, instId); ok {
+				if tmin.After(t0) || tmax.Before(t1) {
+					common.LogError(logPrefix, "not enough funding data for %s@%s", instId, exName)
+					return false
+				}
+			} else {
+				common.LogError(logPrefix, "get funding time range failed for %s@%s", instId, exName)
+				return false
+			}
+		} else {
+			common.LogError(logPrefix, "no funding data for %s@%s", instId, exName)
+			return false
 		}
 	}
 
+	for index, instId := range e.instIds {
+		if common.GetInstType(instId) == common.InstType_Spot {
+			continue
+		}
+
+		it := local.IterFundingRates(t0, t1, exName, instId)
+		e.stream.addSource(newIterSource(it, index, func(r common.FundingRate) (time.Time, interface{}) {
+			return r.Time, r
+		}))
+	}
+
 	return true
 }
 
-func (e *Executor) loadKlines(t0, t1 time.Time, exName common.ExName, klineIntervalSec int, tracker *terminal.TrackerF) bool {
+func (e *Executor) loadKlines(t0, t1 time.Time, exName common.ExName, subs []KlineSubscription, atClose bool) bool {
 	validInstIdsByInterval := local.GetValidKlineInstIds(exName)
-	for _, instId := range e.instIds {
-		if validInstIds, ok := validInstIdsByInterval[klineIntervalSec]; ok {
-			if slices.Contains(validInstIds, instId) {
-				if tmin, tmax, ok := local.GetValidKlineTimeRange(exName, instId, klineIntervalSec); ok {
+	for _, sub := range subs {
+		if _, ok := e.instIdIndexs[sub.InstId]; !ok {
+			common.LogError(logPrefix, "kline subscription %s is not in InstIds", sub.InstId)
+			return false
+		}
+
+		if validInstIds, ok := validInstIdsByInterval[sub.IntervalSec]; ok {
+			if slices.Contains(validInstIds, sub.InstId) {
+				if tmin, tmax, ok := local.GetValidKlineTimeRange(exName, sub.InstId, sub.IntervalSec); ok {
 					if tmin.After(t0) || tmax.Before(t1) {
-						common.LogError(logPrefix, "not enough kline data for %s@%s", instId, exName)
+						common.LogError(logPrefix, "not enough kline data for %s@%s", sub.InstId, exName)
 						return false
 					}
 				} else {
-					common.LogError(logPrefix, "get kline time range failed for %s@%s", instId, exName)
+					common.LogError(logPrefix, "get kline time range failed for %s@%s", sub.InstId, exName)
 					return false
 				}
 			} else {
-				common.LogError(logPrefix, "no kline data for %s@%s", instId, exName)
+				common.LogError(logPrefix, "no kline data for %s@%s", sub.InstId, exName)
 				return false
 			}
 		} else {
-			common.LogError(logPrefix, "invalid kline interval %d for %s@%s", klineIntervalSec, instId, exName)
+			common.LogError(logPrefix, "invalid kline interval %d for %s@%s", sub.IntervalSec, sub.InstId, exName)
 			return false
 		}
 	}
 
-	for instId, index := range e.instIdIndexs {
-		kl := local.LoadKLine(t0, t1, exName, instId, klineIntervalSec, func(i, n int) {
-			tracker.Increment(1.0 / float64(n))
-		})
-		for _, ku := range kl.Units {
-			m := marketInfoUnit{instIdIndex: index, time: ku.Time, data: ku}
-			e.marketInfoSeq = append(e.marketInfoSeq, m)
+	for _, sub := range subs {
+		index := e.instIdIndexs[sub.InstId]
+		interval := sub.IntervalSec
+		if it, ok := local.IterKlineUnits(t0, t1, exName, sub.InstId, interval); ok {
+			e.stream.addSource(newIterSource(it, index, func(ku common.KlineUnit) (time.Time, interface{}) {
+				t := ku.Time
+				if atClose {
+					t = t.Add(time.Duration(interval) * time.Second)
+				}
+				return t, klineEvent{intervalSec: interval, k: ku}
+			}))
+		} else {
+			common.LogError(logPrefix, "invalid kline interval %d for %s@%s", interval, sub.InstId, exName)
+			return false
 		}
 	}
 
@@ -0,0 +1,400 @@
+/*
+- @Author: aztec
+- @Date: 2026-10-16 09:20:05
+- @Description: executor的挂单管理与撮合部分
+- @
+- @Copyright (c) 2024 by aztec, All Rights Reserved.
+*/
+package backtest
+
+import (
+	"cmp"
+	"slices"
+
+	"github.com/aztecqt/dagger/util"
+	"github.com/aztecqt/qbench/common"
+	"github.com/shopspring/decimal"
+)
+
+// 创建一个订单
+func (e *Executor) newOrder(instId string, side common.PosSide, price, amount decimal.Decimal, isSell, taker bool) *Order {
+	e.nextOrderId++
+	return &Order{
+		Id:         e.nextOrderId,
+		InstId:     instId,
+		PosSide:    side,
+		Price:      price,
+		Amount:     amount,
+		IsSell:     isSell,
+		Taker:      taker,
+		Tag:        e.currentOrderTag(),
+		Status:     OrderStatus_Pending,
+		CreateTime: e.Time,
+		UpdateTime: e.Time}
+}
+
+// 提交订单
+// 订单在到达交易所（延迟结束）之前处于Pending状态
+func (e *Executor) submit(o *Order, fnArrive func()) {
+	e.pendingOrders[o.Id] = o
+	e.delay(o.Id, o.InstId, func() {
+		delete(e.pendingOrders, o.Id)
+		fnArrive()
+	})
+}
+
+// 订单是否存在（在途或挂单中）
+func (e *Executor) isOrderKnown(id int64) bool {
+	_, pending := e.pendingOrders[id]
+	_, open := e.openOrdersById[id]
+	return pending || open
+}
+
+func (e *Executor) instIdOfOrder(id int64) string {
+	if o, ok := e.pendingOrders[id]; ok {
+		return o.InstId
+	} else if o, ok := e.openOrdersById[id]; ok {
+		return o.InstId
+	} else {
+		return ""
+	}
+}
+
+// 吃单
+// 如果有盘口数据，先按照盘口深度，计算出最大交易量，对amount进行剪裁，然后计算真实成交价格和真实成交数量
+// 如果没有盘口数据，则跳过这一步
+// 吃单不会驻留，未成交的部分直接撤销
+func (e *Executor) takeOrder(o *Order) {
+	if !e.validateOrder(o) {
+		return
+	}
+
+	price := o.Price
+	amount := o.Amount
+	if v, ok := e.depthOfInsts[o.InstId]; ok {
+		maxAmount := v.GetMaxAmount(price, o.IsSell)
+		if maxAmount.LessThan(amount) {
+			amount = maxAmount
+		}
+
+		// 根据数量，反算成交价格
+		price, amount = v.GetAvgPrice(amount, o.IsSell)
+	}
+
+	// 没有真实盘口时，叠加滑点
+	if !e.useDepth {
+		price = e.applySlippage(o.InstId, price, amount, o.IsSell)
+	}
+
+	// 执行交易
+	if amount.IsPositive() {
+		f := e.execute(o.InstId, o.PosSide, price, amount, o.IsSell, true)
+		o.Filled = f.Amount
+		e.pushFill(o, f)
+	}
+
+	o.Status = util.ValueIf(o.Remaining().IsPositive(), OrderStatus_Cancelled, OrderStatus_Filled)
+	o.UpdateTime = e.Time
+	e.pushOrderUpdate(o)
+}
+
+// 检查订单能否被接受，不能接受时拒绝订单并通知策略
+// 开启ValidateOrders时，先按交易品种规则对齐价格和数量，再检查余额和保证金
+func (e *Executor) validateOrder(o *Order) bool {
+	reason := RejectReason_None
+	if e.cfg.ValidateOrders {
+		reason = e.applyInstrumentRule(o)
+	}
+
+	if reason == RejectReason_None {
+		reason = e.checkSpotOversell(o)
+	}
+
+	if reason == RejectReason_None && e.cfg.ValidateOrders {
+		reason = e.checkAvailable(o)
+	}
+
+	if reason != RejectReason_None {
+		e.rejectOrder(o, reason)
+		return false
+	}
+
+	return true
+}
+
+// 拒绝订单
+func (e *Executor) rejectOrder(o *Order, reason RejectReason) {
+	o.Status = OrderStatus_Rejected
+	o.RejectReason = reason
+	o.UpdateTime = e.Time
+	e.pushOrderUpdate(o)
+}
+
+// 挂单，驻留在executor中等待撮合
+// 挂单为只做maker（post-only），价格穿过当前盘口时直接拒绝，不会按maker价格和费率成交
+func (e *Executor) placeOrder(o *Order) {
+	if !e.validateOrder(o) {
+		return
+	}
+
+	if e.crossesBook(o.InstId, o.Price, o.IsSell) {
+		e.rejectOrder(o, RejectReason_PostOnly)
+		return
+	}
+
+	o.Status = OrderStatus_Open
+	o.UpdateTime = e.Time
+	e.enqueueOrder(o)
+	e.openOrders[o.InstId] = append(e.openOrders[o.InstId], o)
+	e.openOrdersById[o.Id] = o
+	e.pushOrderUpdate(o)
+}
+
+// 挂单价格是否穿过当前盘口（买单不低于卖一，或卖单不高于买一）
+// 没有盘口数据时无法判断，视为不穿过
+func (e *Executor) crossesBook(instId string, price decimal.Decimal, isSell bool) bool {
+	d, ok := e.depthOfInsts[instId]
+	if !ok {
+		return false
+	}
+
+	if isSell {
+		return d.Buy1.IsPositive() && price.LessThanOrEqual(d.Buy1)
+	} else {
+		return d.Sell1.IsPositive() && price.GreaterThanOrEqual(d.Sell1)
+	}
+}
+
+// 挂单进入盘口排队
+func (e *Executor) enqueueOrder(o *Order) {
+	d, ok := e.depthOfInsts[o.InstId]
+	e.fillModel.OnOrderPlaced(o, d, ok && e.useDepth)
+}
+
+// 撤销挂单
+func (e *Executor) cancelOrder(id int64) bool {
+	if o, ok := e.openOrdersById[id]; ok && o.IsOpen() {
+		o.Status = OrderStatus_Cancelled
+		o.UpdateTime = e.Time
+		e.pushOrderUpdate(o)
+		e.removeClosedOrders(o.InstId)
+		return true
+	} else {
+		return false
+	}
+}
+
+// 修改挂单的价格和数量
+// amount为修改后的下单总数量，不能小于等于已成交数量
+func (e *Executor) amendOrder(id int64, price, amount decimal.Decimal) bool {
+	if o, ok := e.openOrdersById[id]; ok && o.IsOpen() {
+		if !price.IsPositive() || amount.LessThanOrEqual(o.Filled) {
+			return false
+		}
+
+		// 按交易品种规则对齐，不满足规则时修改失败
+		if rule, ok := e.rules[o.InstId]; ok && e.cfg.ValidateOrders {
+			price = rule.RoundPrice(price, o.IsSell)
+			amount = rule.RoundAmount(amount)
+			if e.checkInstrumentRule(rule, price, amount) != RejectReason_None || amount.LessThanOrEqual(o.Filled) {
+				return false
+			}
+		}
+
+		// 与下单一样，修改后的价格不能穿过盘口
+		if e.crossesBook(o.InstId, price, o.IsSell) {
+			return false
+		}
+
+		// 修改后的订单同样要检查现货超卖、余额和保证金，不满足时修改失败，订单保持原样
+		oldPrice, oldAmount := o.Price, o.Amount
+		o.Price = price
+		o.Amount = amount
+		reason := e.checkSpotOversell(o)
+		if reason == RejectReason_None && e.cfg.ValidateOrders {
+			reason = e.checkAvailable(o)
+		}
+
+		if reason != RejectReason_None {
+			o.Price = oldPrice
+			o.Amount = oldAmount
+			return false
+		}
+
+		// 修改价格或增加数量时，需要重新排队
+		requeue := !price.Equal(oldPrice) || amount.GreaterThan(oldAmount)
+		o.UpdateTime = e.Time
+		if requeue {
+			e.enqueueOrder(o)
+		}
+		e.pushOrderUpdate(o)
+		return true
+	} else {
+		return false
+	}
+}
+
+// 挂单成交
+// 按挂单价格成交，收取maker手续费。返回实际成交数量
+func (e *Executor) fillOrder(o *Order, amount decimal.Decimal) decimal.Decimal {
+	if amount.GreaterThan(o.Remaining()) {
+		amount = o.Remaining()
+	}
+
+	if !amount.IsPositive() {
+		return decimal.Zero
+	}
+
+	f := e.execute(o.InstId, o.PosSide, o.Price, amount, o.IsSell, false)
+	o.Filled = o.Filled.Add(f.Amount)
+	o.UpdateTime = e.Time
+	if !o.Remaining().IsPositive() {
+		o.Status = OrderStatus_Filled
+	} else if f.Amount.LessThan(amount) {
+		// 成交数量被剪裁（双向持仓的平仓单仓位已经平完，或现货余额不足），剩余部分无法继续成交
+		o.Status = OrderStatus_Cancelled
+	}
+
+	if f.Amount.IsPositive() {
+		e.pushFill(o, f)
+	}
+	e.pushOrderUpdate(o)
+	return f.Amount
+}
+
+// 清理掉已经不在挂单状态的订单
+func (e *Executor) removeClosedOrders(instId string) {
+	orders := e.openOrders[instId]
+	n := 0
+	for _, o := range orders {
+		if o.IsOpen() {
+			orders[n] = o
+			n++
+		} else {
+			delete(e.openOrdersById, o.Id)
+		}
+	}
+
+	if n == 0 {
+		delete(e.openOrders, instId)
+	} else {
+		e.openOrders[instId] = orders[:n]
+	}
+}
+
+// 查询挂单（返回副本），包括尚未到达交易所的在途订单
+func (e *Executor) getOpenOrders(instId string) []Order {
+	orders := make([]Order, 0, len(e.openOrders[instId]))
+	for _, o := range e.openOrders[instId] {
+		orders = append(orders, *o)
+	}
+
+	for _, o := range e.pendingOrders {
+		if o.InstId == instId {
+			orders = append(orders, *o)
+		}
+	}
+
+	slices.SortFunc(orders, func(a, b Order) int {
+		return cmp.Compare(a.Id, b.Id)
+	})
+	return orders
+}
+
+// 按价格优先、时间优先排列挂单（卖单在前，价格从低到高；买单在后，价格从高到低）
+// 同一份流动性先分给排在前面的挂单
+func ordersByPriority(orders []*Order) []*Order {
+	sorted := slices.Clone(orders)
+	slices.SortFunc(sorted, func(a, b *Order) int {
+		if a.IsSell != b.IsSell {
+			return util.ValueIf(a.IsSell, -1, 1)
+		}
+
+		if c := a.Price.Cmp(b.Price); c != 0 {
+			return util.ValueIf(a.IsSell, c, -c)
+		}
+
+		return cmp.Compare(a.Id, b.Id)
+	})
+	return sorted
+}
+
+// 用盘口撮合挂单，成交数量由成交模型决定
+// 同一次盘口更新中，买卖两侧各自记录已被本方挂单消耗的对手盘数量，后面的挂单只能成交剩余的部分
+// 价格优先排列后，越往后的挂单可成交的档位是前面挂单可成交档位的子集，因此扣除已消耗的数量即可
+func (e *Executor) matchOrdersByDepth(instId string, d common.Depth) {
+	orders, ok := e.openOrders[instId]
+	if !ok {
+		return
+	}
+
+	consumed := map[bool]decimal.Decimal{} // IsSell -> 已消耗的对手盘数量
+	for _, o := range ordersByPriority(orders) {
+		available := e.fillModel.FillByDepth(o, d).Sub(consumed[o.IsSell])
+		if available.IsPositive() {
+			consumed[o.IsSell] = consumed[o.IsSell].Add(e.fillOrder(o, available))
+		}
+	}
+
+	e.removeClosedOrders(instId)
+}
+
+// 用市场成交撮合挂单，成交数量由成交模型决定
+// 一笔市场成交的数量只能被分配一次，依次分给各挂单，分完为止
+func (e *Executor) matchOrdersByTrade(instId string, t common.Trade) {
+	orders, ok := e.openOrders[instId]
+	if !ok {
+		return
+	}
+
+	remaining := t.Size
+	for _, o := range ordersByPriority(orders) {
+		if !remaining.IsPositive() {
+			break
+		}
+
+		rt := t
+		rt.Size = remaining
+		amount := decimal.Min(e.fillModel.FillByTrade(o, rt), remaining)
+		remaining = remaining.Sub(e.fillOrder(o, amount))
+	}
+
+	e.removeClosedOrders(instId)
+}
+
+// 缓存一个订单更新事件（保存快照）
+func (e *Executor) pushOrderUpdate(o *Order) {
+	snapshot := *o
+	e.orderEvents = append(e.orderEvents, orderEvent{order: &snapshot})
+}
+
+// 缓存一个成交事件，并记入成交簿
+func (e *Executor) pushFill(o *Order, f Fill) {
+	f.OrderId = o.Id
+	f.StrategyTag = o.Tag
+	e.recordFill(f)
+	e.blotter.Fills = append(e.blotter.Fills, f)
+	e.orderEvents = append(e.orderEvents, orderEvent{fill: &f})
+}
+
+// 将缓存的订单事件推送给策略
+// 策略在回调中可能继续下单，产生新的事件，因此循环直到清空
+func (e *Executor) flushOrderEvents() {
+	for len(e.orderEvents) > 0 {
+		events := e.orderEvents
+		e.orderEvents = nil
+		for _, ev := range events {
+			if e.strategy == nil {
+				continue
+			}
+
+			if ev.fill != nil {
+				e.strategy.OnFill(*ev.fill, e)
+			} else if ev.liquidation != nil {
+				e.strategy.OnPositionLiquidated(*ev.liquidation, e)
+			} else if ev.order != nil {
+				e.strategy.OnOrderUpdate(*ev.order, e)
+			}
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package backtest
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/aztecqt/qbench/common"
+	"github.com/aztecqt/qbench/internal/testutil"
+)
+
+func TestExecutorMakerPostOnly(t *testing.T) {
+	const instId = "btc_usdt_swap"
+
+	cases := []struct {
+		name         string
+		price        float64
+		isSell       bool
+		amendTo      float64 // 非0时在下一个盘口修改价格，修改失败时订单价格不变
+		expectStatus OrderStatus
+		expectReason RejectReason
+		expectPrice  float64
+	}{
+		{"buy below ask rests", 100, false, 0, OrderStatus_Open, RejectReason_None, 100},
+		{"buy at ask rejected", 101, false, 0, OrderStatus_Rejected, RejectReason_PostOnly, 101},
+		{"buy through ask rejected", 105, false, 0, OrderStatus_Rejected, RejectReason_PostOnly, 105},
+		{"sell above bid rests", 100, true, 0, OrderStatus_Open, RejectReason_None, 100},
+		{"sell at bid rejected", 99, true, 0, OrderStatus_Rejected, RejectReason_PostOnly, 99},
+		{"amend through ask fails", 100, false, 101, OrderStatus_Open, RejectReason_None, 100},
+		{"amend inside spread", 100, false, 100.5, OrderStatus_Open, RejectReason_None, 100.5},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			m := newTestMarket(MarketInfoLoadingConfig{InstIds: []string{instId}, Depth: true}).
+				depth(instId, 0, [][2]float64{{99, 10}}, [][2]float64{{101, 10}}).
+				depth(instId, 1, [][2]float64{{99, 10}}, [][2]float64{{101, 10}})
+
+			s := &testStrategy{}
+			s.onDepth = func(_ string, d common.Depth, ctx Context) {
+				if d.Time.Equal(testTime(0)) {
+					ctx.SignalMaker(instId, testutil.Dec(c.price), testutil.Dec(1), c.isSell)
+				} else if c.amendTo != 0 {
+					ctx.AmendOrder(1, testutil.Dec(c.amendTo), testutil.Dec(1))
+				}
+			}
+
+			cfg := ExecutorConfigDefault()
+			cfg.FlattenOnEnd = false
+			m.run(t, cfg, s, map[string]float64{"usdt": 10000})
+
+			o, ok := s.lastUpdate(1)
+			if !ok || o.Status != c.expectStatus || o.RejectReason != c.expectReason {
+				t.Fatalf("expect %v(%v), got %v(%v)", c.expectStatus, c.expectReason, o.Status, o.RejectReason)
+			}
+
+			if !o.Price.Equal(testutil.Dec(c.expectPrice)) {
+				t.Errorf("expect price %v, got %v", c.expectPrice, o.Price)
+			}
+
+			// 穿过盘口的挂单不会按maker价格成交
+			if len(s.fills) != 0 {
+				t.Errorf("expect no fills, got %+v", s.fills)
+			}
+		})
+	}
+}
+
+func TestExecutorMakerMatching(t *testing.T) {
+	const instId = "btc_usdt_swap"
+
+	cases := []struct {
+		name      string
+		fillModel string
+		orders    int // 挂单数量，每个1张
+		expect    []testFill
+	}{
+		{"optimistic fills on first trade", FillModel_Optimistic, 1, []testFill{
+			{1, 1, 100, 1, false},
+		}},
+		{"optimistic shares trade volume", FillModel_Optimistic, 2, []testFill{
+			{1, 1, 100, 1, false},
+			{2, 1, 100, 0.5, false},
+			{2, 2, 100, 0.5, false},
+		}},
+		{"queue waits for volume ahead", FillModel_Queue, 1, []testFill{
+			{1, 2, 100, 0.5, false},
+			{1, 3, 100, 0.5, false},
+		}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			// 0秒时在100挂买单（100档位已有2个），随后市场在100成交1.5和1，3秒时卖盘压到100以下
+			m := newTestMarket(MarketInfoLoadingConfig{InstIds: []string{instId}, Depth: true, Trades: true}).
+				depth(instId, 0, [][2]float64{{100, 2}, {99, 5}}, [][2]float64{{101, 1}}).
+				trade(instId, 1, 100, 1.5, 's').
+				trade(instId, 2, 100, 1, 's').
+				depth(instId, 3, [][2]float64{{99, 1}}, [][2]float64{{99.8, 0.3}, {100, 1}})
+
+			s := &testStrategy{}
+			s.onDepth = func(_ string, d common.Depth, ctx Context) {
+				if d.Time.Equal(testTime(0)) {
+					for i := 0; i < c.orders; i++ {
+						ctx.SignalMaker(instId, testutil.Dec(100), testutil.Dec(1), false)
+					}
+				}
+			}
+
+			cfg := ExecutorConfigDefault()
+			cfg.FillModel = c.fillModel
+			m.run(t, cfg, s, map[string]float64{"usdt": 10000})
+
+			if got := s.fillSummary(); !slices.Equal(got, c.expect) {
+				t.Errorf("expect fills %+v, got %+v", c.expect, got)
+			}
+		})
+	}
+}
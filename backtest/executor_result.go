@@ -0,0 +1,91 @@
+/*
+- @Author: aztec
+- @Date: 2026-10-16 15:36:48
+- @Description: executor的回测结果统计部分
+- @
+- @Copyright (c) 2024 by aztec, All Rights Reserved.
+*/
+package backtest
+
+import (
+	"github.com/aztecqt/dagger/util"
+	"github.com/aztecqt/qbench/common"
+)
+
+// 净值采样，每个采样周期记录一次
+func (e *Executor) sampleNav() {
+	if e.Time.Before(e.navNextSampleTime) {
+		return
+	}
+
+	e.navs = append(e.navs, NavPoint{Time: e.Time, Nav: e.nav().InexactFloat64()})
+	e.breakdowns = append(e.breakdowns, e.ccyBreakdown())
+	e.navNextSampleTime = util.AlignTime(e.Time, e.cfg.NavIntervalMs).Add(e.cfg.navInterval)
+}
+
+// 统计一笔成交
+func (e *Executor) recordFill(f Fill) {
+	if !f.Amount.IsPositive() {
+		return
+	}
+
+	st, ok := e.instStats[f.InstId]
+	if !ok {
+		st = &InstrumentStats{}
+		e.instStats[f.InstId] = st
+	}
+
+	st.Trades++
+	st.Volume += f.Amount.InexactFloat64()
+	if common.GetInstType(f.InstId) == common.InstType_Spot || common.IsUsdtContract(f.InstId) {
+		st.Turnover += f.Price.Mul(f.Amount).InexactFloat64()
+	} else {
+		// 币本位合约的数量本身就是计价币种（USD）
+		st.Turnover += f.Amount.InexactFloat64()
+	}
+
+	if len(f.FeeCcy) > 0 {
+		e.fees[f.FeeCcy] = e.fees[f.FeeCcy].Add(f.Fee)
+	}
+}
+
+// 汇总回测结果
+func (e *Executor) buildResult(s Strategy) *BacktestResult {
+	// 补上最后一个净值点。最后时刻已有采样时，以结束时（可能已自动平仓）的状态为准
+	if n := len(e.navs); n == 0 || e.navs[n-1].Time.Before(e.lastTime) {
+		e.navs = append(e.navs, NavPoint{Time: e.lastTime, Nav: e.nav().InexactFloat64()})
+		e.breakdowns = append(e.breakdowns, e.ccyBreakdown())
+	} else {
+		e.navs[n-1].Nav = e.nav().InexactFloat64()
+		e.breakdowns[n-1] = e.ccyBreakdown()
+	}
+	e.checkInitEquity()
+
+	r := &BacktestResult{
+		Strategy:           s.Class(),
+		StartTime:          e.firstTime,
+		EndTime:            e.lastTime,
+		ValuationCcy:       e.valuationCcy,
+		Navs:               e.navs,
+		Breakdown:          e.breakdowns,
+		PerformanceMetrics: CalcPerformance(e.navs),
+		Instruments:        e.instStats,
+		Fees:               map[string]float64{},
+		Interest:           map[string]float64{}}
+
+	for ccy, fee := range e.fees {
+		r.Fees[ccy] = fee.InexactFloat64()
+	}
+
+	for ccy, interest := range e.interest {
+		r.Interest[ccy] = interest.InexactFloat64()
+	}
+
+	records := map[string][]common.ProfitRecord{}
+	e.forEachPosition(func(instId string, side common.PosSide, pos *common.ContractPosition) {
+		records[pos.MarginCcy] = append(records[pos.MarginCcy], pos.ProfitRecords...)
+	})
+	r.calcWinLoss(records)
+
+	return r
+}
@@ -0,0 +1,184 @@
+/*
+- @Author: aztec
+- @Date: 2026-10-16 20:10:42
+- @Description: executor的订单检查部分（交易品种规则、余额、保证金）
+- @
+- @Copyright (c) 2024 by aztec, All Rights Reserved.
+*/
+package backtest
+
+import (
+	"github.com/aztecqt/dagger/util"
+	"github.com/aztecqt/qbench/common"
+	"github.com/aztecqt/qbench/data/local"
+	"github.com/shopspring/decimal"
+)
+
+// 加载交易品种规则
+// 没有规则文件时只做余额和保证金检查
+func (e *Executor) loadInstrumentRules(ex common.ExName) {
+	if !e.cfg.ValidateOrders {
+		return
+	}
+
+	if rules, ok := local.LoadInstrumentRules(ex); ok {
+		e.rules = rules
+	} else {
+		e.rules = map[string]common.InstrumentRule{}
+		common.LogError(logPrefix, "load instrument rules of %s failed, only balance and margin will be checked", ex)
+	}
+}
+
+// 按交易品种规则对齐价格和数量，并检查下单金额和数量限制
+func (e *Executor) applyInstrumentRule(o *Order) RejectReason {
+	rule, ok := e.rules[o.InstId]
+	if !ok {
+		return RejectReason_None
+	}
+
+	o.Price = rule.RoundPrice(o.Price, o.IsSell)
+	o.Amount = rule.RoundAmount(o.Amount)
+	return e.checkInstrumentRule(rule, o.Price, o.Amount)
+}
+
+func (e *Executor) checkInstrumentRule(rule common.InstrumentRule, price, amount decimal.Decimal) RejectReason {
+	if !price.IsPositive() {
+		return RejectReason_InvalidPrice
+	}
+
+	if !amount.IsPositive() {
+		return RejectReason_InvalidAmount
+	}
+
+	if rule.MaxAmount.IsPositive() && amount.GreaterThan(rule.MaxAmount) {
+		return RejectReason_MaxAmount
+	}
+
+	if rule.MinNotional.IsPositive() && rule.Notional(price, amount).LessThan(rule.MinNotional) {
+		return RejectReason_MinNotional
+	}
+
+	return RejectReason_None
+}
+
+// 检查余额或保证金是否足够
+func (e *Executor) checkAvailable(o *Order) RejectReason {
+	if common.GetInstType(o.InstId) == common.InstType_Spot {
+		return e.checkSpotBuyBalance(o)
+	} else {
+		return e.checkContractMargin(o)
+	}
+}
+
+// 检查现货买单的计价币种余额，已挂出的买单占用的金额视为冻结
+// 与交易所一样，所需金额按订单价格计算并预留手续费（吃单按taker费率，挂单按maker费率）
+// 杠杆账户允许借入计价币种（MarginBorrowQuote）时，不做检查
+func (e *Executor) checkSpotBuyBalance(o *Order) RejectReason {
+	if o.IsSell || e.spotMarginEnabled() && e.cfg.MarginBorrowQuote {
+		return RejectReason_None
+	}
+
+	_, quoteCcy := common.InstId2Ccys(o.InstId)
+	frozen := decimal.Zero
+	for instId, orders := range e.openOrders {
+		if common.GetInstType(instId) != common.InstType_Spot {
+			continue
+		}
+
+		if _, q := common.InstId2Ccys(instId); q != quoteCcy {
+			continue
+		}
+
+		for _, oo := range orders {
+			if oo.IsOpen() && !oo.IsSell && oo.Id != o.Id {
+				frozen = frozen.Add(e.spotBuyCost(oo))
+			}
+		}
+	}
+
+	if e.spotBuyCost(o).Add(frozen).GreaterThan(e.balance[quoteCcy]) {
+		return RejectReason_InsufficientBalance
+	} else {
+		return RejectReason_None
+	}
+}
+
+// 现货买单剩余部分需要的计价币种数量（含手续费）
+func (e *Executor) spotBuyCost(o *Order) decimal.Decimal {
+	feeRate := util.ValueIf(o.Taker, e.cfg.FeeSpotTaker, e.cfg.FeeSpotMaker)
+	return o.Price.Mul(o.Remaining()).Mul(util.DecimalOne.Add(feeRate))
+}
+
+// 订单中开仓部分的数量（平仓部分不需要保证金）
+func (e *Executor) openingAmountOf(o *Order) decimal.Decimal {
+	switch o.PosSide {
+	case common.PosSide_Long:
+		return util.ValueIf(o.IsSell, decimal.Zero, o.Remaining())
+	case common.PosSide_Short:
+		return util.ValueIf(o.IsSell, o.Remaining(), decimal.Zero)
+	default:
+		pos := decimal.Zero
+		if p, ok := e.positions[o.InstId]; ok {
+			pos = p.Position
+		}
+
+		if pos.IsZero() || pos.IsPositive() != o.IsSell {
+			// 同向加仓
+			return o.Remaining()
+		} else {
+			// 反向，先平后开
+			return decimal.Max(o.Remaining().Sub(pos.Abs()), decimal.Zero)
+		}
+	}
+}
+
+// 以指定价格开仓所需的保证金
+func (e *Executor) marginOf(instId string, price, amount decimal.Decimal) decimal.Decimal {
+	lev := e.leverageOf(instId)
+	if !lev.IsPositive() || !price.IsPositive() {
+		return decimal.Zero
+	}
+
+	notional := util.ValueIf(common.IsUsdtContract(instId), amount.Mul(price), amount.Div(price))
+	return notional.Div(lev)
+}
+
+// 检查合约订单的保证金
+// 可用保证金 = 余额 + 浮动盈亏 - 仓位占用的保证金 - 其他挂单开仓部分占用的保证金
+// 不计算保证金（杠杆为0）的品种不做检查
+func (e *Executor) checkContractMargin(o *Order) RejectReason {
+	if !e.leverageOf(o.InstId).IsPositive() {
+		return RejectReason_None
+	}
+
+	required := e.marginOf(o.InstId, o.Price, e.openingAmountOf(o))
+	if required.IsZero() {
+		return RejectReason_None
+	}
+
+	marginCcy := common.InstId2MarginCcy(o.InstId)
+	available := e.balance[marginCcy].Add(e.unrealizedPnl[marginCcy])
+	e.forEachPosition(func(instId string, side common.PosSide, pos *common.ContractPosition) {
+		if pos.MarginCcy == marginCcy {
+			available = available.Sub(pos.InitialMargin())
+		}
+	})
+
+	for instId, orders := range e.openOrders {
+		if common.GetInstType(instId) == common.InstType_Spot || common.InstId2MarginCcy(instId) != marginCcy {
+			continue
+		}
+
+		for _, oo := range orders {
+			if oo.IsOpen() && oo.Id != o.Id {
+				available = available.Sub(e.marginOf(instId, oo.Price, e.openingAmountOf(oo)))
+			}
+		}
+	}
+
+	if required.GreaterThan(available) {
+		return RejectReason_InsufficientMargin
+	} else {
+		return RejectReason_None
+	}
+}
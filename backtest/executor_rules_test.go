@@ -0,0 +1,60 @@
+package backtest
+
+import (
+	"testing"
+
+	"github.com/aztecqt/qbench/common"
+	"github.com/aztecqt/qbench/internal/testutil"
+)
+
+func TestExecutorSpotBuyBalanceIncludesFee(t *testing.T) {
+	const instId = "btc_usdt"
+
+	cases := []struct {
+		name         string
+		taker        bool
+		price        float64
+		amount       float64
+		expectStatus OrderStatus
+	}{
+		{"taker within balance", true, 100, 9.99, OrderStatus_Filled}, // 999 + 0.999手续费
+		{"taker short of fee", true, 100, 10, OrderStatus_Rejected},   // 1000 + 1手续费
+		{"maker within balance", false, 99, 10.09, OrderStatus_Open},  // 998.91 + 0.499455手续费
+		{"maker short of fee", false, 99, 10.1, OrderStatus_Rejected}, // 999.9 + 0.49995手续费
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			m := newTestMarket(MarketInfoLoadingConfig{InstIds: []string{instId}, Depth: true}).
+				depth(instId, 0, [][2]float64{{99, 100}}, [][2]float64{{100, 100}})
+
+			s := &testStrategy{}
+			s.onDepth = func(_ string, d common.Depth, ctx Context) {
+				if c.taker {
+					ctx.SignalTaker(instId, testutil.Dec(c.price), testutil.Dec(c.amount), false)
+				} else {
+					ctx.SignalMaker(instId, testutil.Dec(c.price), testutil.Dec(c.amount), false)
+				}
+			}
+
+			cfg := ExecutorConfigDefault()
+			cfg.ValidateOrders = true
+			cfg.FeeSpotTaker = testutil.Dec(0.001)
+			cfg.FeeSpotMaker = testutil.Dec(0.0005)
+			e, _ := m.run(t, cfg, s, map[string]float64{"usdt": 1000})
+
+			o, ok := s.lastUpdate(1)
+			if !ok || o.Status != c.expectStatus {
+				t.Fatalf("expect %v, got %v", c.expectStatus, o.Status)
+			}
+
+			if o.Status == OrderStatus_Rejected && o.RejectReason != RejectReason_InsufficientBalance {
+				t.Errorf("expect reason %v, got %v", RejectReason_InsufficientBalance, o.RejectReason)
+			}
+
+			if e.balance["usdt"].IsNegative() {
+				t.Errorf("quote balance went negative: %v", e.balance["usdt"])
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+/*
+- @Author: aztec
+- @Date: 2026-10-16 21:06:38
+- @Description: executor的滑点部分
+- @
+- @Copyright (c) 2024 by aztec, All Rights Reserved.
+*/
+package backtest
+
+import (
+	"time"
+
+	"github.com/aztecqt/dagger/util"
+	"github.com/shopspring/decimal"
+)
+
+// 查询品种的市场统计，不使用滑点模型时返回nil
+func (e *Executor) marketStatsOf(instId string) *marketStatsTracker {
+	if e.slippageModel == nil {
+		return nil
+	}
+
+	ms, ok := e.marketStats[instId]
+	if !ok {
+		ms = newMarketStatsTracker(e.cfg.slippageWindow)
+		e.marketStats[instId] = ms
+	}
+	return ms
+}
+
+// 统计成交量
+func (e *Executor) onMarketVolume(instId string, t time.Time, volume decimal.Decimal) {
+	if ms := e.marketStatsOf(instId); ms != nil {
+		ms.onVolume(t, volume)
+	}
+}
+
+// 在吃单价格上叠加不利的滑点
+func (e *Executor) applySlippage(instId string, price, amount decimal.Decimal, isSell bool) decimal.Decimal {
+	ms := e.marketStatsOf(instId)
+	if ms == nil || !amount.IsPositive() {
+		return price
+	}
+
+	slip := decimal.NewFromFloat(e.slippageModel.Slippage(instId, price, amount, isSell, ms.stats()))
+	if isSell {
+		return price.Mul(util.DecimalOne.Sub(slip))
+	} else {
+		return price.Mul(util.DecimalOne.Add(slip))
+	}
+}
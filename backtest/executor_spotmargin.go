@@ -0,0 +1,129 @@
+/*
+- @Author: aztec
+- @Date: 2026-10-16 19:05:26
+- @Description: executor的现货超卖处理与杠杆借贷部分
+- @
+- @Copyright (c) 2024 by aztec, All Rights Reserved.
+*/
+package backtest
+
+import (
+	"time"
+
+	"github.com/aztecqt/qbench/common"
+	"github.com/shopspring/decimal"
+)
+
+// 现货余额不足时的处理方式，用于ExecutorConfig
+const (
+	SpotOversell_Allow  = ""       // 允许余额为负（旧的行为，不计利息）
+	SpotOversell_Reject = "reject" // 拒绝超出余额的卖单
+	SpotOversell_Margin = "margin" // 杠杆账户：不足的部分自动借入，按小时计息，有余额时自动还款
+)
+
+func (e *Executor) spotMarginEnabled() bool {
+	return e.cfg.SpotOversell == SpotOversell_Margin
+}
+
+// 检查现货卖单是否超卖
+// 已挂出的卖单占用的数量视为冻结
+func (e *Executor) checkSpotOversell(o *Order) RejectReason {
+	if !e.rejectSpotOversell() ||
+		!o.IsSell ||
+		common.GetInstType(o.InstId) != common.InstType_Spot {
+		return RejectReason_None
+	}
+
+	baseCcy, _ := common.InstId2Ccys(o.InstId)
+	frozen := decimal.Zero
+	for instId, orders := range e.openOrders {
+		if common.GetInstType(instId) != common.InstType_Spot {
+			continue
+		}
+
+		if b, _ := common.InstId2Ccys(instId); b != baseCcy {
+			continue
+		}
+
+		for _, oo := range orders {
+			if oo.IsOpen() && oo.IsSell && oo.Id != o.Id {
+				frozen = frozen.Add(oo.Remaining())
+			}
+		}
+	}
+
+	if o.Remaining().Add(frozen).GreaterThan(e.balance[baseCcy]) {
+		return RejectReason_InsufficientBalance
+	} else {
+		return RejectReason_None
+	}
+}
+
+// 是否拒绝现货超卖
+// 开启订单检查（ValidateOrders）时，非杠杆账户同样不允许超卖
+func (e *Executor) rejectSpotOversell() bool {
+	return e.cfg.SpotOversell == SpotOversell_Reject ||
+		e.cfg.ValidateOrders && !e.spotMarginEnabled()
+}
+
+// 不允许超卖时，现货卖出数量不能超过余额
+func (e *Executor) clipSpotSell(baseCcy string, amount decimal.Decimal) decimal.Decimal {
+	if !e.rejectSpotOversell() {
+		return amount
+	}
+
+	return decimal.Max(decimal.Min(amount, e.balance[baseCcy]), decimal.Zero)
+}
+
+// 杠杆账户的借贷结算
+// borrow为true时，余额为负的部分记为负债，余额归零；余额为正且有负债时，自动还款
+// borrow为false时只还款，余额为负保持原样（与非杠杆账户相同）
+// 现货成交、合约盈亏和手续费、资金费改变余额之后都会调用，每次计息之前也会对所有负债结算一次
+func (e *Executor) settleSpotMargin(ccy string, borrow bool) {
+	if !e.spotMarginEnabled() {
+		return
+	}
+
+	bal := e.balance[ccy]
+	if bal.IsNegative() && borrow {
+		e.liabilities[ccy] = e.liabilities[ccy].Add(bal.Neg())
+		e.balance[ccy] = decimal.Zero
+	} else if liab := e.liabilities[ccy]; liab.IsPositive() && bal.IsPositive() {
+		repay := decimal.Min(liab, bal)
+		e.liabilities[ccy] = liab.Sub(repay)
+		e.balance[ccy] = bal.Sub(repay)
+	}
+}
+
+// 按小时计息，利息计入负债
+// 每个整点结算一次，利率为MarginInterestRates中配置的小时利率
+func (e *Executor) accrueMarginInterest() {
+	if !e.spotMarginEnabled() {
+		return
+	}
+
+	if e.nextInterestTime.IsZero() {
+		e.nextInterestTime = e.Time.Truncate(time.Hour).Add(time.Hour)
+		return
+	}
+
+	for !e.Time.Before(e.nextInterestTime) {
+		for ccy, liab := range e.liabilities {
+			if !liab.IsPositive() {
+				continue
+			}
+
+			// 计息前先用余额还款，只对仍未还清的部分计息
+			e.settleSpotMargin(ccy, false)
+			liab = e.liabilities[ccy]
+			if !liab.IsPositive() {
+				continue
+			}
+
+			interest := liab.Mul(e.cfg.MarginInterestRates[ccy])
+			e.liabilities[ccy] = liab.Add(interest)
+			e.interest[ccy] = e.interest[ccy].Add(interest)
+		}
+		e.nextInterestTime = e.nextInterestTime.Add(time.Hour)
+	}
+}
@@ -0,0 +1,67 @@
+package backtest
+
+import (
+	"testing"
+
+	"github.com/aztecqt/qbench/common"
+	"github.com/aztecqt/qbench/internal/testutil"
+	"github.com/shopspring/decimal"
+)
+
+func TestExecutorSpotMarginRepay(t *testing.T) {
+	const spot = "btc_usdt"
+	const swap = "btc_usd_swap"
+
+	cases := []struct {
+		name            string
+		closeSwap       bool    // 1秒时以50平掉空单（盈利以btc计）
+		fundingRate     float64 // 0.5秒时的资金费率，0表示没有资金费
+		expectLiability float64 // 计息之后的btc负债
+	}{
+		{"no repayment", false, 0, 1.01},
+		{"funding repays", false, 0.001, 0.9999},
+		{"contract profit repays", true, 0, 0},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			// 资金费在0.5秒按中间价100结算，1秒时盘口跌到50，3600秒时计息
+			m := newTestMarket(MarketInfoLoadingConfig{InstIds: []string{spot, swap}, Depth: true, FundingRates: true}).
+				depth(spot, 0, [][2]float64{{100, 10}}, [][2]float64{{101, 10}}).
+				depth(swap, 0, [][2]float64{{99, 10000}}, [][2]float64{{101, 10000}})
+			if c.fundingRate != 0 {
+				m.funding(swap, 0.5, c.fundingRate)
+			}
+			m.depth(swap, 1, [][2]float64{{49, 10000}}, [][2]float64{{50, 10000}}).
+				depth(spot, 3600, [][2]float64{{100, 10}}, [][2]float64{{101, 10}})
+
+			s := &testStrategy{}
+			s.onDepth = func(instId string, d common.Depth, ctx Context) {
+				switch {
+				case instId == spot && d.Time.Equal(testTime(0)):
+					// 超卖1个btc，借入btc
+					ctx.SignalTaker(spot, testutil.Dec(100), testutil.Dec(1), true)
+				case instId == swap && d.Time.Equal(testTime(0)):
+					// 币本位空单1000张（USD）
+					ctx.SignalTaker(swap, testutil.Dec(99), testutil.Dec(1000), true)
+				case instId == swap && d.Time.Equal(testTime(1)) && c.closeSwap:
+					ctx.SignalTaker(swap, testutil.Dec(50), testutil.Dec(1000), false)
+				}
+			}
+
+			cfg := ExecutorConfigDefault()
+			cfg.SpotOversell = SpotOversell_Margin
+			cfg.MarginInterestRates = map[string]decimal.Decimal{"btc": testutil.Dec(0.01)}
+			e, _ := m.run(t, cfg, s, map[string]float64{"usdt": 1000})
+
+			if liab := e.GetLiability("btc"); !liab.Equal(testutil.Dec(c.expectLiability)) {
+				t.Errorf("expect liability %v, got %v", c.expectLiability, liab)
+			}
+
+			// 有负债时不应同时有btc余额
+			if bal := e.balance["btc"]; bal.IsPositive() && e.GetLiability("btc").IsPositive() {
+				t.Errorf("balance %v left while liability %v accrues interest", bal, e.GetLiability("btc"))
+			}
+		})
+	}
+}
@@ -0,0 +1,245 @@
+package backtest
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/aztecqt/dagger/util"
+	"github.com/aztecqt/qbench/common"
+	"github.com/aztecqt/qbench/data/bars"
+	"github.com/aztecqt/qbench/internal/testutil"
+)
+
+var testT0 = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func testTime(sec float64) time.Time {
+	return testT0.Add(time.Duration(sec * float64(time.Second)))
+}
+
+// 测试用行情，按时间顺序追加，直接作为ReplayData回放
+type testMarket struct {
+	cfg   MarketInfoLoadingConfig
+	units []marketInfoUnit
+	t1    time.Time
+}
+
+func newTestMarket(cfg MarketInfoLoadingConfig) *testMarket {
+	return &testMarket{cfg: cfg, t1: testT0.Add(time.Hour)}
+}
+
+func (m *testMarket) add(instId string, sec float64, data interface{}) *testMarket {
+	m.units = append(m.units, marketInfoUnit{instIdIndex: slices.Index(m.cfg.InstIds, instId), time: testTime(sec), data: data})
+	return m
+}
+
+func (m *testMarket) depth(instId string, sec float64, bids, asks [][2]float64) *testMarket {
+	return m.add(instId, sec, testutil.Depth(testTime(sec), bids, asks))
+}
+
+func (m *testMarket) trade(instId string, sec float64, price, size float64, side byte) *testMarket {
+	return m.add(instId, sec, testutil.Trade(testTime(sec), price, size, side))
+}
+
+func (m *testMarket) funding(instId string, sec float64, rate float64) *testMarket {
+	return m.add(instId, sec, common.FundingRate{Time: testTime(sec), Rate: testutil.Dec(rate)})
+}
+
+// 用给定的配置和初始资产回放行情
+func (m *testMarket) run(t *testing.T, cfg ExecutorConfig, s Strategy, balance map[string]float64) (*Executor, *BacktestResult) {
+	t.Helper()
+	cfg.ShowCharts = false
+	cfg.HideProgress = true
+
+	e := newExecutor(cfg)
+	for ccy, v := range balance {
+		e.SetBalance(ccy, testutil.Dec(v))
+	}
+
+	rd := &ReplayData{cfg: m.cfg, t0: testT0, t1: m.t1, units: m.units}
+	r, ok := e.RunReplay(s, rd)
+	if !ok {
+		t.Fatalf("run replay failed")
+	}
+	return e, r
+}
+
+// 测试用策略，回调转发给测试函数，并记录订单事件
+type testStrategy struct {
+	BaseStrategy
+	onDepth func(instId string, d common.Depth, c Context)
+	onTimer func(id int64, tag string, c Context)
+	onBar   func(instId string, spec bars.Spec, b bars.Bar, c Context)
+
+	updates []Order
+	fills   []Fill
+}
+
+func (s *testStrategy) Class() string {
+	return "test"
+}
+
+func (s *testStrategy) MarketInfoRequired() MarketInfoLoadingConfig {
+	return MarketInfoLoadingConfig{}
+}
+
+func (s *testStrategy) OnDepth(instId string, d common.Depth, c Context) {
+	if s.onDepth != nil {
+		s.onDepth(instId, d, c)
+	}
+}
+
+func (s *testStrategy) OnTimer(id int64, tag string, c Context) {
+	if s.onTimer != nil {
+		s.onTimer(id, tag, c)
+	}
+}
+
+func (s *testStrategy) OnBar(instId string, spec bars.Spec, b bars.Bar, c Context) {
+	if s.onBar != nil {
+		s.onBar(instId, spec, b, c)
+	}
+}
+
+func (s *testStrategy) OnOrderUpdate(o Order, c Context) {
+	s.updates = append(s.updates, o)
+}
+
+func (s *testStrategy) OnFill(f Fill, c Context) {
+	s.fills = append(s.fills, f)
+}
+
+// 某订单最后一次推送的状态
+func (s *testStrategy) lastUpdate(id int64) (Order, bool) {
+	for i := len(s.updates) - 1; i >= 0; i-- {
+		if s.updates[i].Id == id {
+			return s.updates[i], true
+		}
+	}
+	return Order{}, false
+}
+
+// 成交记录摘要，时间以相对testT0的秒数表示
+type testFill struct {
+	orderId            int64
+	sec, price, amount float64
+	taker              bool
+}
+
+func (s *testStrategy) fillSummary() []testFill {
+	fills := make([]testFill, 0, len(s.fills))
+	for _, f := range s.fills {
+		fills = append(fills, testFill{
+			orderId: f.OrderId,
+			sec:     f.Time.Sub(testT0).Seconds(),
+			price:   f.Price.InexactFloat64(),
+			amount:  f.Amount.InexactFloat64(),
+			taker:   f.Taker})
+	}
+	return fills
+}
+
+func TestExecutorHedgeMode(t *testing.T) {
+	const instId = "btc_usdt_swap"
+	type position struct{ long, short, net float64 }
+
+	cases := []struct {
+		name   string
+		orders func(c Context) // 在第一个盘口下单
+		expect position
+	}{
+		{"open both sides", func(c Context) {
+			c.SignalTakerOfSide(instId, common.PosSide_Long, testutil.Dec(101), testutil.Dec(2), false)
+			c.SignalTakerOfSide(instId, common.PosSide_Short, testutil.Dec(100), testutil.Dec(1), true)
+		}, position{2, -1, 1}},
+		{"close part of long", func(c Context) {
+			c.SignalTakerOfSide(instId, common.PosSide_Long, testutil.Dec(101), testutil.Dec(2), false)
+			c.SignalTakerOfSide(instId, common.PosSide_Short, testutil.Dec(100), testutil.Dec(3), true)
+			c.SignalTakerOfSide(instId, common.PosSide_Long, testutil.Dec(100), testutil.Dec(0.5), true)
+		}, position{1.5, -3, -1.5}},
+		{"closing more than position is clipped", func(c Context) {
+			c.SignalTakerOfSide(instId, common.PosSide_Short, testutil.Dec(100), testutil.Dec(1), true)
+			c.SignalTakerOfSide(instId, common.PosSide_Short, testutil.Dec(101), testutil.Dec(4), false)
+		}, position{0, 0, 0}},
+		{"net side is ignored", func(c Context) {
+			c.SignalTaker(instId, testutil.Dec(101), testutil.Dec(1), false)
+		}, position{0, 0, 0}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			m := newTestMarket(MarketInfoLoadingConfig{InstIds: []string{instId}, Depth: true}).
+				depth(instId, 0, [][2]float64{{100, 10}}, [][2]float64{{101, 10}}).
+				depth(instId, 1, [][2]float64{{100, 10}}, [][2]float64{{101, 10}})
+
+			got := position{}
+			s := &testStrategy{}
+			s.onDepth = func(_ string, d common.Depth, ctx Context) {
+				if d.Time.Equal(testTime(0)) {
+					c.orders(ctx)
+				} else {
+					long, _ := ctx.GetPositionOfSide(instId, common.PosSide_Long)
+					short, _ := ctx.GetPositionOfSide(instId, common.PosSide_Short)
+					net, avg := ctx.GetPosition(instId)
+					if !avg.IsZero() {
+						t.Errorf("expect zero average price for hedge mode net position, got %v", avg)
+					}
+					got = position{long.InexactFloat64(), short.InexactFloat64(), net.InexactFloat64()}
+				}
+			}
+
+			cfg := ExecutorConfigDefault()
+			cfg.HedgeModeInstIds = []string{instId}
+			m.run(t, cfg, s, map[string]float64{"usdt": 10000})
+
+			if got != c.expect {
+				t.Errorf("expect %+v, got %+v", c.expect, got)
+			}
+		})
+	}
+}
+
+func TestExecutorFunding(t *testing.T) {
+	const instId = "btc_usdt_swap"
+
+	cases := []struct {
+		name          string
+		isSell        bool
+		rate          float64
+		expectPayment float64
+	}{
+		{"long pays", false, 0.001, -0.1005},
+		{"short receives", true, 0.001, 0.1005},
+		{"long receives negative rate", false, -0.001, 0.1005},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			// 盘口中间价100.5，开仓后在1秒时结算资金费
+			m := newTestMarket(MarketInfoLoadingConfig{InstIds: []string{instId}, Depth: true, FundingRates: true}).
+				depth(instId, 0, [][2]float64{{100, 10}}, [][2]float64{{101, 10}}).
+				funding(instId, 1, c.rate)
+
+			s := &testStrategy{}
+			s.onDepth = func(_ string, d common.Depth, ctx Context) {
+				ctx.SignalTaker(instId, util.ValueIf(c.isSell, d.Buy1, d.Sell1), testutil.Dec(1), c.isSell)
+			}
+
+			e, _ := m.run(t, ExecutorConfigDefault(), s, map[string]float64{"usdt": 10000})
+
+			// 资金费只计入余额一次，不计入仓位的交易收益
+			pos := e.positions[instId]
+			if !pos.TotalFunding.Equal(testutil.Dec(c.expectPayment)) {
+				t.Errorf("expect funding %v, got %v", c.expectPayment, pos.TotalFunding)
+			}
+
+			if !e.balance["usdt"].Equal(testutil.Dec(10000 + c.expectPayment)) {
+				t.Errorf("expect balance %v, got %v", 10000+c.expectPayment, e.balance["usdt"])
+			}
+
+			if !pos.TotalProfit().Equal(pos.UnRealizedProfit) {
+				t.Errorf("expect total profit %v without funding, got %v", pos.UnRealizedProfit, pos.TotalProfit())
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+/*
+- @Author: aztec
+- @Date: 2026-10-16 21:32:50
+- @Description: executor的定时器部分
+- @
+- @Copyright (c) 2024 by aztec, All Rights Reserved.
+*/
+package backtest
+
+import (
+	"time"
+
+	"github.com/aztecqt/dagger/util"
+)
+
+// 定时器
+type timer struct {
+	id       int64
+	tag      string
+	interval time.Duration // 大于0表示周期定时器
+}
+
+// 定时器事件，作为一个行情单元插入行情流
+type timerEvent struct {
+	id int64
+}
+
+// 设置一次性定时器，在at时刻触发。at早于当前时间时，在下一个行情单元之前触发
+func (e *Executor) setTimer(at time.Time, tag string) int64 {
+	e.nextTimerId++
+	t := &timer{id: e.nextTimerId, tag: tag}
+	e.timers[t.id] = t
+	e.scheduleTimer(t, util.ValueIf(at.Before(e.Time), e.Time, at))
+	return t.id
+}
+
+// 设置周期定时器，在interval的整数倍时刻触发（如每个整点）
+func (e *Executor) setInterval(interval time.Duration, tag string) int64 {
+	if interval <= 0 {
+		return 0
+	}
+
+	e.nextTimerId++
+	t := &timer{id: e.nextTimerId, tag: tag, interval: interval}
+	e.timers[t.id] = t
+	e.scheduleTimer(t, e.Time.Truncate(interval).Add(interval))
+	return t.id
+}
+
+func (e *Executor) cancelTimer(id int64) bool {
+	if _, ok := e.timers[id]; ok {
+		delete(e.timers, id)
+		return true
+	} else {
+		return false
+	}
+}
+
+// 把定时器插入行情流，超过回放结束时间的不再触发
+func (e *Executor) scheduleTimer(t *timer, at time.Time) {
+	if !e.endTime.IsZero() && at.After(e.endTime) {
+		delete(e.timers, t.id)
+		return
+	}
+
+	e.stream.push(marketInfoUnit{instIdIndex: -1, time: at, data: timerEvent{id: t.id}})
+}
+
+// 触发定时器
+func (e *Executor) fireTimer(s Strategy, te timerEvent) {
+	t, ok := e.timers[te.id]
+	if !ok {
+		// 已撤销
+		return
+	}
+
+	if t.interval > 0 {
+		e.scheduleTimer(t, e.Time.Add(t.interval))
+	} else {
+		delete(e.timers, t.id)
+	}
+
+	s.OnTimer(t.id, t.tag, e)
+}
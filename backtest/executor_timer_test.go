@@ -0,0 +1,72 @@
+package backtest
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/aztecqt/qbench/common"
+)
+
+func TestExecutorTimer(t *testing.T) {
+	const instId = "btc_usdt_swap"
+
+	cases := []struct {
+		name   string
+		setup  func(c Context) // 在第一个盘口设置定时器
+		cancel float64         // 大于0时，在该时刻的定时器回调中撤销定时器
+		expect []string
+	}{
+		{"timer fires once", func(c Context) {
+			c.SetTimer(testTime(1.5), "a")
+		}, 0, []string{"depth@0", "depth@1", "a@1.5", "depth@2"}},
+		{"timer fires after same time depth", func(c Context) {
+			c.SetTimer(testTime(1), "a")
+		}, 0, []string{"depth@0", "depth@1", "a@1", "depth@2"}},
+		{"past timer fires at once", func(c Context) {
+			c.SetTimer(testTime(-1), "a")
+		}, 0, []string{"depth@0", "a@0", "depth@1", "depth@2"}},
+		{"timer after end never fires", func(c Context) {
+			c.SetTimer(testTime(10), "a")
+		}, 0, []string{"depth@0", "depth@1", "depth@2"}},
+		{"interval on whole seconds", func(c Context) {
+			c.SetInterval(time.Second, "i")
+		}, 0, []string{"depth@0", "depth@1", "i@1", "depth@2", "i@2", "i@3"}},
+		{"cancelled interval stops", func(c Context) {
+			c.SetInterval(time.Second, "i")
+		}, 2, []string{"depth@0", "depth@1", "i@1", "depth@2", "i@2"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			m := newTestMarket(MarketInfoLoadingConfig{InstIds: []string{instId}, Depth: true}).
+				depth(instId, 0, [][2]float64{{100, 10}}, [][2]float64{{101, 10}}).
+				depth(instId, 1, [][2]float64{{100, 10}}, [][2]float64{{101, 10}}).
+				depth(instId, 2, [][2]float64{{100, 10}}, [][2]float64{{101, 10}})
+			m.t1 = testTime(3)
+
+			events := []string{}
+			s := &testStrategy{}
+			s.onDepth = func(_ string, d common.Depth, ctx Context) {
+				events = append(events, fmt.Sprintf("depth@%v", d.Time.Sub(testT0).Seconds()))
+				if d.Time.Equal(testTime(0)) {
+					c.setup(ctx)
+				}
+			}
+			s.onTimer = func(id int64, tag string, ctx Context) {
+				sec := ctx.GetTime().Sub(testT0).Seconds()
+				events = append(events, fmt.Sprintf("%s@%v", tag, sec))
+				if c.cancel > 0 && sec == c.cancel && !ctx.CancelTimer(id) {
+					t.Errorf("cancel timer %d failed", id)
+				}
+			}
+
+			m.run(t, ExecutorConfigDefault(), s, map[string]float64{"usdt": 10000})
+
+			if !slices.Equal(events, c.expect) {
+				t.Errorf("expect %v, got %v", c.expect, events)
+			}
+		})
+	}
+}
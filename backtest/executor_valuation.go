@@ -0,0 +1,262 @@
+/*
+- @Author: aztec
+- @Date: 2026-10-16 18:34:17
+- @Description: executor的估值部分
+- @
+- @Copyright (c) 2024 by aztec, All Rights Reserved.
+*/
+package backtest
+
+import (
+	"slices"
+	"time"
+
+	"github.com/aztecqt/dagger/util"
+	"github.com/aztecqt/qbench/common"
+	"github.com/shopspring/decimal"
+)
+
+// 单个币种的资产情况
+type CcyValue struct {
+	Balance       float64 `json:"balance"`        // 余额
+	UnrealizedPnl float64 `json:"unrealized_pnl"` // 浮动盈亏
+	Liability     float64 `json:"liability"`      // 负债（现货杠杆借款及利息）
+	Equity        float64 `json:"equity"`         // 权益（余额+浮动盈亏-负债），以估值币种计
+}
+
+// 某一时刻的分币种资产情况
+type CcyBreakdown struct {
+	Time   time.Time           `json:"time"`
+	Equity float64             `json:"equity"` // 总权益，以估值币种计
+	Ccys   map[string]CcyValue `json:"ccys"`
+}
+
+// 确定估值币种
+func (e *Executor) initValuationCcy() {
+	if len(e.cfg.ValuationCcy) > 0 {
+		e.valuationCcy = e.cfg.ValuationCcy
+		return
+	}
+
+	ccys := []string{}
+	for ccy := range e.initBalance {
+		ccys = append(ccys, ccy)
+	}
+	slices.Sort(ccys)
+
+	if len(ccys) == 0 {
+		e.valuationCcy = "usdt"
+	} else if slices.Contains(ccys, "usdt") {
+		e.valuationCcy = "usdt"
+	} else {
+		e.valuationCcy = ccys[0]
+	}
+}
+
+// 汇率图中的一条边：1个from=价格（invert时为1/价格）个to
+// 价格取instIds中第一个有价格的品种（现货优先于合约）
+type priceEdge struct {
+	to      string
+	instIds []string
+	invert  bool
+}
+
+// 取边的当前汇率，所有品种都还没有价格时返回false
+func (e *Executor) edgeRate(edge priceEdge) (decimal.Decimal, bool) {
+	for _, instId := range edge.instIds {
+		if px, ok := e.priceOfInsts[instId]; ok && px.IsPositive() {
+			if edge.invert {
+				return util.DecimalOne.Div(px), true
+			} else {
+				return px, true
+			}
+		}
+	}
+
+	return decimal.Zero, false
+}
+
+// 汇率图，缓存在executor中
+// 现货btc_usdt提供btc<->usdt的双向汇率。没有对应现货（或现货还没有价格）时，用合约的价格代替
+// 图只记录币种之间经由哪些品种相连，汇率在使用时按最新价格计算，所以只有品种表变化时才需要重建
+func (e *Executor) priceGraph() map[string][]priceEdge {
+	if e.valuationGraph != nil {
+		return e.valuationGraph
+	}
+
+	graph := map[string][]priceEdge{}
+	pairs := map[[2]string]int{} // 交易对->在graph[baseCcy]中的位置
+	addPair := func(instId string) {
+		baseCcy, quoteCcy := common.InstId2Ccys(instId)
+		if i, ok := pairs[[2]string{baseCcy, quoteCcy}]; ok {
+			graph[baseCcy][i].instIds = append(graph[baseCcy][i].instIds, instId)
+			for j := range graph[quoteCcy] {
+				if graph[quoteCcy][j].to == baseCcy && graph[quoteCcy][j].invert {
+					graph[quoteCcy][j].instIds = append(graph[quoteCcy][j].instIds, instId)
+				}
+			}
+			return
+		}
+
+		pairs[[2]string{baseCcy, quoteCcy}] = len(graph[baseCcy])
+		graph[baseCcy] = append(graph[baseCcy], priceEdge{to: quoteCcy, instIds: []string{instId}})
+		graph[quoteCcy] = append(graph[quoteCcy], priceEdge{to: baseCcy, instIds: []string{instId}, invert: true})
+	}
+
+	// 按品种表顺序遍历，保证结果确定
+	for _, instId := range e.instIds {
+		if common.GetInstType(instId) == common.InstType_Spot {
+			addPair(instId)
+		}
+	}
+
+	for _, instId := range e.instIds {
+		if common.GetInstType(instId) != common.InstType_Spot {
+			addPair(instId)
+		}
+	}
+
+	e.valuationGraph = graph
+	return graph
+}
+
+// 把一定数量的srcCcy折算成dstCcy
+// 没有直接的交易对时，经由中间币种多次折算（如eth->btc->usdt），取经过币种最少的路径
+func (e *Executor) exchangeToCcy(srcCcy, dstCcy string, amount decimal.Decimal) (decimal.Decimal, bool) {
+	if srcCcy == dstCcy || amount.IsZero() {
+		return amount, true
+	}
+
+	// 广度优先搜索
+	graph := e.priceGraph()
+	rates := map[string]decimal.Decimal{srcCcy: util.DecimalOne}
+	queue := []string{srcCcy}
+	for len(queue) > 0 {
+		ccy := queue[0]
+		queue = queue[1:]
+		for _, edge := range graph[ccy] {
+			if _, ok := rates[edge.to]; ok {
+				continue
+			}
+
+			rate, ok := e.edgeRate(edge)
+			if !ok {
+				continue
+			}
+
+			rates[edge.to] = rates[ccy].Mul(rate)
+			if edge.to == dstCcy {
+				return amount.Mul(rates[edge.to]), true
+			}
+			queue = append(queue, edge.to)
+		}
+	}
+
+	return decimal.Zero, false
+}
+
+// 折算成估值币种，无法折算时记录一次错误并按0计
+func (e *Executor) valueOf(ccy string, amount decimal.Decimal) decimal.Decimal {
+	if v, ok := e.exchangeToCcy(ccy, e.valuationCcy, amount); ok {
+		return v
+	} else {
+		if !e.unvaluedCcys[ccy] {
+			e.unvaluedCcys[ccy] = true
+			common.LogError(logPrefix, "can not convert %s to %s, treat as zero", ccy, e.valuationCcy)
+		}
+		return decimal.Zero
+	}
+}
+
+// 当前总权益（余额+浮动盈亏-负债），以估值币种计
+func (e *Executor) equity() decimal.Decimal {
+	total := decimal.Zero
+	for ccy, amount := range e.balance {
+		total = total.Add(e.valueOf(ccy, amount))
+	}
+
+	for ccy, amount := range e.unrealizedPnl {
+		total = total.Add(e.valueOf(ccy, amount))
+	}
+
+	for ccy, amount := range e.liabilities {
+		total = total.Sub(e.valueOf(ccy, amount))
+	}
+
+	return total
+}
+
+// 计算当前单位净值
+// 将当前所有资产折算成估值币种，除以同样折算的初始权益
+func (e *Executor) nav() decimal.Decimal {
+	if !e.initEquityReady {
+		// 初始权益按第一次能够完整折算时的价格计算
+		initEquity := decimal.Zero
+		for ccy, amount := range e.initBalance {
+			if v, ok := e.exchangeToCcy(ccy, e.valuationCcy, amount); ok {
+				initEquity = initEquity.Add(v)
+			} else {
+				return util.DecimalOne
+			}
+		}
+
+		e.initEquity = initEquity
+		e.initEquityReady = true
+	}
+
+	if e.initEquity.IsZero() {
+		return util.DecimalOne
+	}
+
+	return e.equity().Div(e.initEquity)
+}
+
+// 回测结束时检查初始权益是否已确定
+// 某个初始资产币种始终无法折算时，整个回测的单位净值都是1，绩效指标没有意义，需要明确报错
+func (e *Executor) checkInitEquity() bool {
+	if e.initEquityReady {
+		return true
+	}
+
+	ccys := []string{}
+	for ccy, amount := range e.initBalance {
+		if _, ok := e.exchangeToCcy(ccy, e.valuationCcy, amount); !ok {
+			ccys = append(ccys, ccy)
+		}
+	}
+	slices.Sort(ccys)
+
+	common.LogError(logPrefix, "initial equity never valued, can not convert %v to %s, nav and performance metrics are meaningless", ccys, e.valuationCcy)
+	return false
+}
+
+// 分币种的资产情况
+func (e *Executor) ccyBreakdown() CcyBreakdown {
+	cb := CcyBreakdown{Time: e.Time, Ccys: map[string]CcyValue{}}
+	total := decimal.Zero
+	fnAdd := func(ccy string, balance, pnl, liability decimal.Decimal) {
+		cv := cb.Ccys[ccy]
+		cv.Balance += balance.InexactFloat64()
+		cv.UnrealizedPnl += pnl.InexactFloat64()
+		cv.Liability += liability.InexactFloat64()
+		v := e.valueOf(ccy, balance.Add(pnl).Sub(liability))
+		cv.Equity += v.InexactFloat64()
+		total = total.Add(v)
+		cb.Ccys[ccy] = cv
+	}
+
+	for ccy, amount := range e.balance {
+		fnAdd(ccy, amount, decimal.Zero, decimal.Zero)
+	}
+
+	for ccy, amount := range e.unrealizedPnl {
+		fnAdd(ccy, decimal.Zero, amount, decimal.Zero)
+	}
+
+	for ccy, amount := range e.liabilities {
+		fnAdd(ccy, decimal.Zero, decimal.Zero, amount)
+	}
+
+	cb.Equity = total.InexactFloat64()
+	return cb
+}
@@ -0,0 +1,166 @@
+/*
+- @Author: aztec
+- @Date: 2026-10-16 10:02:31
+- @Description: 挂单成交模型
+- @
+- @Copyright (c) 2024 by aztec, All Rights Reserved.
+*/
+package backtest
+
+import (
+	"github.com/aztecqt/qbench/common"
+	"github.com/shopspring/decimal"
+)
+
+// 挂单成交模型
+// 决定一个驻留的挂单，在盘口变化和市场成交时能成交多少
+// 不同模型对成交的假设不同，可以用来衡量策略对成交假设的敏感程度
+type FillModel interface {
+	// 挂单进入盘口（包括修改价格后重新进入）时调用
+	// hasDepth表示d为真实的盘口数据（而不是由ticker模拟出来的）
+	OnOrderPlaced(o *Order, d common.Depth, hasDepth bool)
+
+	// 盘口更新时调用，返回挂单可成交的数量
+	FillByDepth(o *Order, d common.Depth) decimal.Decimal
+
+	// 市场成交时调用，返回挂单可成交的数量
+	// t.Size为这笔成交中尚未分配给其他挂单的剩余数量，返回值不能超过它
+	FillByTrade(o *Order, t common.Trade) decimal.Decimal
+}
+
+// 内置的成交模型名称，用于ExecutorConfig
+const (
+	FillModel_Optimistic = "optimistic"
+	FillModel_Queue      = "queue"
+)
+
+func newFillModel(name string) FillModel {
+	switch name {
+	case FillModel_Queue:
+		return &QueueFillModel{}
+	case FillModel_Optimistic, "":
+		return &OptimisticFillModel{}
+	default:
+		common.LogError(logPrefix, "unknown fill model %s, use %s instead", name, FillModel_Optimistic)
+		return &OptimisticFillModel{}
+	}
+}
+
+// 盘口穿过挂单价格时，可以吃掉挂单价格以内的对手盘数量
+func fillByCrossingDepth(o *Order, d common.Depth) decimal.Decimal {
+	if o.IsSell {
+		if d.Buy1.IsPositive() && d.Buy1.GreaterThanOrEqual(o.Price) {
+			return d.GetMaxAmount(o.Price, o.IsSell)
+		}
+	} else {
+		if d.Sell1.IsPositive() && d.Sell1.LessThanOrEqual(o.Price) {
+			return d.GetMaxAmount(o.Price, o.IsSell)
+		}
+	}
+
+	return decimal.Zero
+}
+
+// 乐观成交模型
+// 盘口：对手盘价格穿过挂单价格时，吃掉挂单价格以内的对手盘数量
+// 成交：成交价格穿过（或等于）挂单价格时，认为挂单可以成交，成交数量不超过市场成交数量
+// 不考虑排队
+type OptimisticFillModel struct {
+}
+
+func (m *OptimisticFillModel) OnOrderPlaced(o *Order, d common.Depth, hasDepth bool) {
+	o.QueueAhead = decimal.Zero
+}
+
+func (m *OptimisticFillModel) FillByDepth(o *Order, d common.Depth) decimal.Decimal {
+	return fillByCrossingDepth(o, d)
+}
+
+func (m *OptimisticFillModel) FillByTrade(o *Order, t common.Trade) decimal.Decimal {
+	if o.IsSell && t.Price.GreaterThanOrEqual(o.Price) || !o.IsSell && t.Price.LessThanOrEqual(o.Price) {
+		return decimal.Min(t.Size, o.Remaining())
+	} else {
+		return decimal.Zero
+	}
+}
+
+// 排队成交模型（需要同时加载Depth和Trades）
+// 挂单加入某个价格档位时，排在该档位现有数量（DepthUnit.Amount）之后
+// 只有在该价格上成交的数量超过前方排队数量后，挂单才开始成交
+// 前方排队数量只减不增：档位数量减少时，认为减少的是排在前面的挂单
+// 成交价格穿过挂单价格时，说明整个档位已经被吃掉，挂单直接成交
+type QueueFillModel struct {
+}
+
+// 查询挂单所在档位的数量
+// visible表示挂单价格在这份盘口的可见范围内（不比最差一档更差），此时档位不存在说明数量为0
+// 挂单价格在可见档位之外时，盘口无法说明该档位的情况，visible为false
+func (m *QueueFillModel) levelAmount(o *Order, d common.Depth) (amount decimal.Decimal, visible bool) {
+	dus := d.Bids
+	if o.IsSell {
+		dus = d.Asks
+	}
+
+	if len(dus) == 0 {
+		return decimal.Zero, false
+	}
+
+	for _, du := range dus {
+		if du.Price.Equal(o.Price) {
+			return du.Amount, true
+		}
+	}
+
+	worst := dus[len(dus)-1].Price
+	if o.IsSell {
+		return decimal.Zero, o.Price.LessThan(worst)
+	} else {
+		return decimal.Zero, o.Price.GreaterThan(worst)
+	}
+}
+
+func (m *QueueFillModel) OnOrderPlaced(o *Order, d common.Depth, hasDepth bool) {
+	if hasDepth {
+		o.QueueAhead, _ = m.levelAmount(o, d)
+	} else {
+		o.QueueAhead = decimal.Zero
+	}
+}
+
+func (m *QueueFillModel) FillByDepth(o *Order, d common.Depth) decimal.Decimal {
+	if amount := fillByCrossingDepth(o, d); amount.IsPositive() {
+		o.QueueAhead = decimal.Zero
+		return amount
+	}
+
+	// 档位数量减少，前方排队数量随之减少。档位不在可见范围内时保持不变
+	if levelAmount, visible := m.levelAmount(o, d); visible && levelAmount.LessThan(o.QueueAhead) {
+		o.QueueAhead = levelAmount
+	}
+
+	return decimal.Zero
+}
+
+func (m *QueueFillModel) FillByTrade(o *Order, t common.Trade) decimal.Decimal {
+	if o.IsSell && t.Price.GreaterThan(o.Price) || !o.IsSell && t.Price.LessThan(o.Price) {
+		// 成交价格穿过挂单价格
+		o.QueueAhead = decimal.Zero
+		return decimal.Min(t.Size, o.Remaining())
+	} else if t.Price.Equal(o.Price) {
+		// 在挂单价格上成交，只有对手方主动成交才能消耗本方队列
+		if o.IsSell && t.Side == 's' || !o.IsSell && t.Side == 'b' {
+			return decimal.Zero
+		}
+
+		if t.Size.LessThanOrEqual(o.QueueAhead) {
+			o.QueueAhead = o.QueueAhead.Sub(t.Size)
+			return decimal.Zero
+		} else {
+			amount := t.Size.Sub(o.QueueAhead)
+			o.QueueAhead = decimal.Zero
+			return decimal.Min(amount, o.Remaining())
+		}
+	} else {
+		return decimal.Zero
+	}
+}
@@ -0,0 +1,156 @@
+package backtest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aztecqt/qbench/common"
+	"github.com/aztecqt/qbench/internal/testutil"
+	"github.com/shopspring/decimal"
+)
+
+func testDepth(bids, asks [][2]float64) common.Depth {
+	return testutil.Depth(time.Time{}, bids, asks)
+}
+
+func testOrder(price, amount float64, isSell bool) *Order {
+	return &Order{Price: testutil.Dec(price), Amount: testutil.Dec(amount), IsSell: isSell}
+}
+
+func TestOptimisticFillByTrade(t *testing.T) {
+	cases := []struct {
+		name   string
+		order  *Order
+		trade  common.Trade
+		expect float64
+	}{
+		{"buy crossed", testOrder(100, 1, false), testutil.Trade(time.Time{}, 99, 0.4, 0), 0.4},
+		{"buy at price", testOrder(100, 1, false), testutil.Trade(time.Time{}, 100, 0.4, 0), 0.4},
+		{"buy not reached", testOrder(100, 1, false), testutil.Trade(time.Time{}, 101, 5, 0), 0},
+		{"sell crossed", testOrder(100, 1, true), testutil.Trade(time.Time{}, 101, 0.3, 0), 0.3},
+		{"sell not reached", testOrder(100, 1, true), testutil.Trade(time.Time{}, 99, 5, 0), 0},
+		{"clipped by remaining", testOrder(100, 1, false), testutil.Trade(time.Time{}, 99, 5, 0), 1},
+	}
+
+	m := &OptimisticFillModel{}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			m.OnOrderPlaced(c.order, common.Depth{}, false)
+			if got := m.FillByTrade(c.order, c.trade); !got.Equal(testutil.Dec(c.expect)) {
+				t.Errorf("expect %v, got %v", c.expect, got)
+			}
+		})
+	}
+}
+
+func TestOptimisticFillByDepth(t *testing.T) {
+	cases := []struct {
+		name   string
+		order  *Order
+		depth  common.Depth
+		expect float64
+	}{
+		{"buy crossed", testOrder(100, 5, false), testDepth([][2]float64{{98, 1}}, [][2]float64{{99, 1}, {100, 2}, {101, 3}}), 3},
+		{"buy not crossed", testOrder(100, 5, false), testDepth([][2]float64{{99, 1}}, [][2]float64{{101, 1}}), 0},
+		{"sell crossed", testOrder(100, 5, true), testDepth([][2]float64{{101, 1}, {100, 1}, {99, 4}}, [][2]float64{{102, 1}}), 2},
+		{"sell not crossed", testOrder(100, 5, true), testDepth([][2]float64{{99, 1}}, [][2]float64{{101, 1}}), 0},
+	}
+
+	m := &OptimisticFillModel{}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := m.FillByDepth(c.order, c.depth); !got.Equal(testutil.Dec(c.expect)) {
+				t.Errorf("expect %v, got %v", c.expect, got)
+			}
+		})
+	}
+}
+
+func TestQueueFillModel(t *testing.T) {
+	// 买单挂在100，初始排在100档位的2个之后
+	placed := testDepth([][2]float64{{100, 2}, {99, 5}}, [][2]float64{{101, 1}})
+
+	type step struct {
+		depth       *common.Depth // 非nil时为盘口更新
+		trade       *common.Trade // 非nil时为市场成交
+		expectFill  float64
+		expectAhead float64
+	}
+
+	depth := func(bids, asks [][2]float64) *common.Depth {
+		d := testDepth(bids, asks)
+		return &d
+	}
+	trade := func(price, size float64, side byte) *common.Trade {
+		t := testutil.Trade(time.Time{}, price, size, side)
+		return &t
+	}
+
+	cases := []struct {
+		name  string
+		steps []step
+	}{
+		{"trade consumes queue first", []step{
+			{trade: trade(100, 1.5, 's'), expectFill: 0, expectAhead: 0.5},
+			{trade: trade(100, 1, 's'), expectFill: 0.5, expectAhead: 0},
+		}},
+		{"same side trade does not consume queue", []step{
+			{trade: trade(100, 5, 'b'), expectFill: 0, expectAhead: 2},
+		}},
+		{"crossing trade fills directly", []step{
+			{trade: trade(99, 0.7, 's'), expectFill: 0.7, expectAhead: 0},
+		}},
+		{"level shrinks queue", []step{
+			{depth: depth([][2]float64{{100, 0.5}, {99, 5}}, [][2]float64{{101, 1}}), expectFill: 0, expectAhead: 0.5},
+		}},
+		{"level grows keeps queue", []step{
+			{depth: depth([][2]float64{{100, 8}, {99, 5}}, [][2]float64{{101, 1}}), expectFill: 0, expectAhead: 2},
+		}},
+		{"level gone inside visible range", []step{
+			{depth: depth([][2]float64{{99.5, 1}, {99, 5}}, [][2]float64{{101, 1}}), expectFill: 0, expectAhead: 0},
+		}},
+		{"level outside snapshot keeps queue", []step{
+			{depth: depth([][2]float64{{100.5, 1}, {100.2, 1}}, [][2]float64{{101, 1}}), expectFill: 0, expectAhead: 2},
+		}},
+		{"depth crosses order", []step{
+			{depth: depth([][2]float64{{99.5, 1}}, [][2]float64{{99.8, 0.3}, {100, 0.4}, {100.5, 1}}), expectFill: 0.7, expectAhead: 0},
+		}},
+	}
+
+	m := &QueueFillModel{}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			o := testOrder(100, 1, false)
+			m.OnOrderPlaced(o, placed, true)
+			for i, s := range c.steps {
+				got := decimal.Zero
+				if s.depth != nil {
+					got = m.FillByDepth(o, *s.depth)
+				} else {
+					got = m.FillByTrade(o, *s.trade)
+				}
+
+				if !got.Equal(testutil.Dec(s.expectFill)) {
+					t.Errorf("step %d: expect fill %v, got %v", i, s.expectFill, got)
+				}
+				if !o.QueueAhead.Equal(testutil.Dec(s.expectAhead)) {
+					t.Errorf("step %d: expect queue ahead %v, got %v", i, s.expectAhead, o.QueueAhead)
+				}
+				o.Filled = o.Filled.Add(got)
+			}
+		})
+	}
+}
+
+func TestQueueFillModelWithoutDepth(t *testing.T) {
+	m := &QueueFillModel{}
+	o := testOrder(100, 1, true)
+	m.OnOrderPlaced(o, common.Depth{}, false)
+	if !o.QueueAhead.IsZero() {
+		t.Errorf("expect empty queue without real depth, got %v", o.QueueAhead)
+	}
+
+	if got := m.FillByTrade(o, testutil.Trade(time.Time{}, 100, 0.6, 'b')); !got.Equal(testutil.Dec(0.6)) {
+		t.Errorf("expect fill 0.6, got %v", got)
+	}
+}
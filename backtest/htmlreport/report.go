@@ -0,0 +1,160 @@
+/*
+- @Author: aztec
+- @Date: 2026-10-16 16:05:33
+- @Description: 生成自包含的静态html回测报告（不依赖任何外部资源）
+- @
+- @Copyright (c) 2024 by aztec, All Rights Reserved.
+*/
+package htmlreport
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"math"
+	"time"
+
+	"github.com/aztecqt/dagger/util"
+	"github.com/aztecqt/qbench/common"
+)
+
+const logPrefix = "htmlreport"
+
+//go:embed report.tmpl
+var reportTemplate string
+
+// 数据点
+type Point struct {
+	Time  time.Time
+	Value float64
+}
+
+// 买卖点
+type Marker struct {
+	Time   time.Time
+	Value  float64
+	IsSell bool
+}
+
+// 一条曲线
+type Line struct {
+	Name   string
+	Points []Point
+}
+
+// 一张图表，多条曲线共用一个纵轴
+type Chart struct {
+	Title   string
+	Lines   []Line
+	Markers []Marker
+}
+
+// 报告
+// 所有图表共享横轴（时间），缩放、拖动时联动
+type Report struct {
+	Title  string
+	Infos  []string // 文字信息，每行一条
+	Charts []*Chart
+}
+
+func NewReport(title string) *Report {
+	return &Report{Title: title}
+}
+
+// 添加一行文字信息
+func (r *Report) AddInfo(format string, args ...interface{}) {
+	r.Infos = append(r.Infos, fmt.Sprintf(format, args...))
+}
+
+// 添加一张图表
+func (r *Report) AddChart(title string) *Chart {
+	c := &Chart{Title: title}
+	r.Charts = append(r.Charts, c)
+	return c
+}
+
+// 添加一条曲线
+func (c *Chart) AddLine(name string, points []Point) {
+	c.Lines = append(c.Lines, Line{Name: name, Points: points})
+}
+
+// 添加买卖点
+func (c *Chart) AddMarkers(markers []Marker) {
+	c.Markers = append(c.Markers, markers...)
+}
+
+// 嵌入页面中的图表数据。时间为毫秒时间戳，点为[t, v]数组以减小体积
+type chartJson struct {
+	Title   string      `json:"title"`
+	Lines   []lineJson  `json:"lines"`
+	Markers [][]float64 `json:"markers"` // [t, v, isSell]
+}
+
+type lineJson struct {
+	Name   string      `json:"name"`
+	Points [][]float64 `json:"points"`
+}
+
+func (r *Report) chartsJson() ([]byte, error) {
+	charts := make([]chartJson, 0, len(r.Charts))
+	for _, c := range r.Charts {
+		cj := chartJson{Title: c.Title, Lines: []lineJson{}, Markers: [][]float64{}}
+		for _, l := range c.Lines {
+			lj := lineJson{Name: l.Name, Points: make([][]float64, 0, len(l.Points))}
+			for _, p := range l.Points {
+				if math.IsNaN(p.Value) || math.IsInf(p.Value, 0) {
+					continue
+				}
+				lj.Points = append(lj.Points, []float64{float64(p.Time.UnixMilli()), p.Value})
+			}
+			cj.Lines = append(cj.Lines, lj)
+		}
+
+		for _, m := range c.Markers {
+			cj.Markers = append(cj.Markers, []float64{float64(m.Time.UnixMilli()), m.Value, util.ValueIf(m.IsSell, 1.0, 0.0)})
+		}
+
+		charts = append(charts, cj)
+	}
+
+	return json.Marshal(charts)
+}
+
+// 渲染成html
+func (r *Report) Render() ([]byte, bool) {
+	tmpl, err := template.New("report").Parse(reportTemplate)
+	if err != nil {
+		common.LogError(logPrefix, "parse template failed: %s", err.Error())
+		return nil, false
+	}
+
+	data, err := r.chartsJson()
+	if err != nil {
+		common.LogError(logPrefix, "marshal charts failed: %s", err.Error())
+		return nil, false
+	}
+
+	buf := bytes.Buffer{}
+	err = tmpl.Execute(&buf, map[string]interface{}{
+		"Title":  r.Title,
+		"Infos":  r.Infos,
+		"Charts": template.JS(data),
+	})
+	if err != nil {
+		common.LogError(logPrefix, "render report failed: %s", err.Error())
+		return nil, false
+	}
+
+	return buf.Bytes(), true
+}
+
+// 渲染并保存到文件
+func (r *Report) SaveToFile(path string) bool {
+	if b, ok := r.Render(); ok {
+		return common.SaveFile(logPrefix, path, b)
+	} else {
+		return false
+	}
+}
@@ -0,0 +1,158 @@
+/*
+- @Author: aztec
+- @Date: 2026-10-17 00:41:58
+- @Description: 回测任务。从json/yaml任务文件读取回测所需的全部参数，无界面运行并输出结果
+- @
+- @Copyright (c) 2024 by aztec, All Rights Reserved.
+*/
+package job
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aztecqt/qbench/backtest"
+	"github.com/aztecqt/qbench/common"
+	"github.com/shopspring/decimal"
+	"gopkg.in/yaml.v2"
+)
+
+const logPrefix = "job"
+
+// 回测任务
+// 策略需要事先通过backtest.RegisterStrategy注册，Params原样传给策略工厂
+type Job struct {
+	LocalDataPath string                     `json:"local_data_path"`
+	Exchange      common.ExName              `json:"exchange"`
+	StartTime     string                     `json:"start_time"` // 2006-01-02 15:04:05或2006-01-02，本地时区
+	EndTime       string                     `json:"end_time"`
+	InitBalance   map[string]decimal.Decimal `json:"init_balance"`
+	Executor      backtest.ExecutorConfig    `json:"executor"` // 未填写的字段取默认值
+	Strategy      string                     `json:"strategy"`
+	Params        json.RawMessage            `json:"params"`
+	OutputDir     string                     `json:"output_dir"` // 默认为./output/策略名称/当前时间
+}
+
+// 从文件加载任务，按扩展名区分yaml（.yaml/.yml）和json
+// 注意yaml采用1.1规范，y/n/on/off等作为键或值时会被解析成布尔值，需要加引号
+func LoadJob(path string) (*Job, bool) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		common.LogError(logPrefix, "read job file %s failed: %s", path, err.Error())
+		return nil, false
+	}
+
+	// yaml先转换成json，这样所有结构体只需要json标签
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext == ".yaml" || ext == ".yml" {
+		var v interface{}
+		if err := yaml.Unmarshal(b, &v); err != nil {
+			common.LogError(logPrefix, "parse job file %s failed: %s", path, err.Error())
+			return nil, false
+		}
+
+		if b, err = json.Marshal(yamlToJson(v)); err != nil {
+			common.LogError(logPrefix, "convert job file %s failed: %s", path, err.Error())
+			return nil, false
+		}
+	}
+
+	j := &Job{Executor: backtest.ExecutorConfigDefault()}
+	if err := json.Unmarshal(b, j); err != nil {
+		common.LogError(logPrefix, "parse job file %s failed: %s", path, err.Error())
+		return nil, false
+	}
+
+	return j, true
+}
+
+// yaml解析出的map键为interface{}，json无法直接序列化，需要逐层转换
+func yamlToJson(v interface{}) interface{} {
+	switch vv := v.(type) {
+	case map[interface{}]interface{}:
+		m := map[string]interface{}{}
+		for k, val := range vv {
+			m[fmt.Sprint(k)] = yamlToJson(val)
+		}
+		return m
+	case []interface{}:
+		for i, val := range vv {
+			vv[i] = yamlToJson(val)
+		}
+		return vv
+	default:
+		return v
+	}
+}
+
+func parseTime(s string) (time.Time, bool) {
+	for _, layout := range []string{time.DateTime, time.DateOnly} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// 运行任务
+// 输出目录中包含：回测结果（result.json）、净值序列（nav.csv）、成交记录（trades.csv/trades.jsonl）和html报告（report.html）
+func (j *Job) Run() (*backtest.BacktestResult, bool) {
+	t0, ok0 := parseTime(j.StartTime)
+	t1, ok1 := parseTime(j.EndTime)
+	if !ok0 || !ok1 || !t0.Before(t1) {
+		common.LogError(logPrefix, "invalid time range: %s ~ %s", j.StartTime, j.EndTime)
+		return nil, false
+	}
+
+	s, ok := backtest.NewStrategy(j.Strategy, j.Params)
+	if !ok {
+		return nil, false
+	}
+
+	outDir := j.OutputDir
+	if len(outDir) == 0 {
+		outDir = fmt.Sprintf("./output/%s/%s", j.Strategy, time.Now().Format("2006-01-02.15-04-05"))
+	}
+
+	// 无界面运行，报告直接输出到输出目录
+	cfg := j.Executor
+	cfg.ShowCharts = true
+	cfg.ChartsRenderer = backtest.ChartsRenderer_Html
+	cfg.ChartsDir = outDir
+
+	e := backtest.NewExecutor(j.LocalDataPath, cfg)
+	for ccy, amount := range j.InitBalance {
+		e.SetBalance(ccy, amount)
+	}
+
+	r, ok := e.Run(s, j.Exchange, t0, t1)
+	if !ok {
+		return nil, false
+	}
+
+	ok = r.SaveJSON(filepath.Join(outDir, "result.json"))
+	ok = saveNavs(filepath.Join(outDir, "nav.csv"), r.Navs) && ok
+	ok = e.Blotter().SaveCSV(filepath.Join(outDir, "trades.csv")) && ok
+	ok = e.Blotter().SaveJSONL(filepath.Join(outDir, "trades.jsonl")) && ok
+	if ok {
+		common.LogNormal(logPrefix, "job finished, output saved to %s", outDir)
+	}
+
+	return r, ok
+}
+
+// 加载并运行任务文件，outDir不为空时覆盖任务中的输出目录
+func RunFile(path, outDir string) (*backtest.BacktestResult, bool) {
+	if j, ok := LoadJob(path); ok {
+		if len(outDir) > 0 {
+			j.OutputDir = outDir
+		}
+		return j.Run()
+	} else {
+		return nil, false
+	}
+}
@@ -0,0 +1,63 @@
+/*
+- @Author: aztec
+- @Date: 2026-10-17 11:52:08
+- @Description: 命令行入口，供qbench以及研究仓库自己的main调用
+- @
+- @Copyright (c) 2024 by aztec, All Rights Reserved.
+*/
+package job
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/aztecqt/qbench/backtest"
+)
+
+const usage = `usage: %s -job job.yaml [-out dir]
+       %s -list
+
+strategies are registered by backtest.RegisterStrategy in the init of their package.
+this binary only knows the strategies linked into it; to run your own strategies,
+blank-import your strategy packages in your own main and call job.Main() from it:
+
+    import (
+        _ "your/repo/strategies"
+        "github.com/aztecqt/qbench/backtest/job"
+    )
+
+    func main() { job.Main() }
+
+flags:
+`
+
+// 命令行入口
+// 用法：-job 任务文件 [-out 输出目录]，或 -list 列出已注册的策略
+// 只能运行已经链接进来的策略，研究仓库可以在自己的main中匿名导入策略包，然后调用Main
+func Main() {
+	jobPath := flag.String("job", "", "job file (json/yaml)")
+	outDir := flag.String("out", "", "output directory, overrides output_dir in job file")
+	list := flag.Bool("list", false, "list registered strategies")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), usage, os.Args[0], os.Args[0])
+		flag.PrintDefaults()
+		fmt.Fprintf(flag.CommandLine.Output(), "\nregistered strategies: %s\n", strings.Join(backtest.RegisteredStrategies(), ", "))
+	}
+	flag.Parse()
+
+	if *list {
+		fmt.Println(strings.Join(backtest.RegisteredStrategies(), "\n"))
+		return
+	}
+
+	if len(*jobPath) == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if _, ok := RunFile(*jobPath, *outDir); !ok {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,24 @@
+/*
+- @Author: aztec
+- @Date: 2026-10-17 00:58:31
+- @Description: 回测任务的输出文件
+- @
+- @Copyright (c) 2024 by aztec, All Rights Reserved.
+*/
+package job
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/aztecqt/qbench/backtest"
+	"github.com/aztecqt/qbench/common"
+)
+
+func saveNavs(path string, navs []backtest.NavPoint) bool {
+	rows := [][]string{{"time", "nav"}}
+	for _, p := range navs {
+		rows = append(rows, []string{p.Time.Format(time.DateTime), strconv.FormatFloat(p.Nav, 'f', -1, 64)})
+	}
+	return common.SaveCSV(logPrefix, path, rows)
+}
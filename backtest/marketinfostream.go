@@ -0,0 +1,131 @@
+/*
+- @Author: aztec
+- @Date: 2026-10-16 14:37:52
+- @Description: 行情流。把多个按时间有序的行情源，归并成一个按时间有序的行情流
+- @
+- @Copyright (c) 2024 by aztec, All Rights Reserved.
+*/
+package backtest
+
+import (
+	"container/heap"
+	"math"
+	"time"
+
+	"github.com/aztecqt/qbench/data/local"
+)
+
+// 行情源，按时间顺序逐个产出行情单元
+// 一般一个品种的一种行情就是一个行情源
+type marketInfoSource interface {
+	next() (marketInfoUnit, bool)
+}
+
+// 把data/local的按天迭代器包装成行情源
+type iterSource[T any] struct {
+	it          *local.DayIterator[T]
+	instIdIndex int
+	fnUnit      func(v T) (time.Time, interface{}) // 从原始数据中取出时间，并转换成行情单元的数据
+}
+
+func newIterSource[T any](it *local.DayIterator[T], instIdIndex int, fnUnit func(v T) (time.Time, interface{})) *iterSource[T] {
+	return &iterSource[T]{it: it, instIdIndex: instIdIndex, fnUnit: fnUnit}
+}
+
+func (s *iterSource[T]) next() (marketInfoUnit, bool) {
+	if v, ok := s.it.Next(); ok {
+		t, data := s.fnUnit(v)
+		return marketInfoUnit{instIdIndex: s.instIdIndex, time: t, data: data}, true
+	} else {
+		return marketInfoUnit{}, false
+	}
+}
+
+// 堆中的元素：某个行情源的当前行情单元
+type marketInfoStreamItem struct {
+	unit marketInfoUnit
+	src  marketInfoSource // 为nil表示单独插入的行情单元
+	seq  int              // 加入顺序，时间相同时按加入顺序输出
+}
+
+type marketInfoHeap []marketInfoStreamItem
+
+func (h marketInfoHeap) Len() int { return len(h) }
+func (h marketInfoHeap) Less(i, j int) bool {
+	if c := h[i].unit.time.Compare(h[j].unit.time); c != 0 {
+		return c < 0
+	} else {
+		return h[i].seq < h[j].seq
+	}
+}
+func (h marketInfoHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *marketInfoHeap) Push(x any)   { *h = append(*h, x.(marketInfoStreamItem)) }
+func (h *marketInfoHeap) Pop() any {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
+
+// 行情流
+// 每个行情源在堆中只保留一个行情单元，取出一个就从同一个行情源补充一个
+// 行情源本身是惰性加载的，所以整个回测过程中，内存里只有各行情源当天的数据
+type marketInfoStream struct {
+	h     marketInfoHeap
+	nsrc  int
+	npush int
+}
+
+func newMarketInfoStream() *marketInfoStream {
+	return &marketInfoStream{h: marketInfoHeap{}}
+}
+
+// 加入一个行情源，空的行情源直接忽略
+func (s *marketInfoStream) addSource(src marketInfoSource) {
+	if u, ok := src.next(); ok {
+		heap.Push(&s.h, marketInfoStreamItem{unit: u, src: src, seq: s.nsrc})
+	}
+	s.nsrc++
+}
+
+// 是否已经没有行情
+func (s *marketInfoStream) empty() bool {
+	return s.h.Len() == 0
+}
+
+// 下一个行情单元的时间
+func (s *marketInfoStream) peekTime() (time.Time, bool) {
+	if s.empty() {
+		return time.Time{}, false
+	} else {
+		return s.h[0].unit.time, true
+	}
+}
+
+// 插入一个单独的行情单元（如定时器）
+// 时间相同时排在所有行情源之后，多个单元之间按插入顺序
+func (s *marketInfoStream) push(u marketInfoUnit) {
+	s.npush++
+	heap.Push(&s.h, marketInfoStreamItem{unit: u, seq: math.MaxInt32 + s.npush})
+}
+
+// 取出时间最早的行情单元
+func (s *marketInfoStream) next() (marketInfoUnit, bool) {
+	if s.empty() {
+		return marketInfoUnit{}, false
+	}
+
+	item := s.h[0]
+	u := item.unit
+	if item.src == nil {
+		heap.Pop(&s.h)
+	} else if nu, ok := item.src.next(); ok {
+		s.h[0].unit = nu
+		heap.Fix(&s.h, 0)
+	} else {
+		heap.Pop(&s.h)
+	}
+
+	return u, true
+}
@@ -19,9 +19,13 @@ type marketInfoUnit struct {
 
 	// 行情类型。可以是以下类型：
 	// common.Ticker
-	// common.KlineUnit
+	// klineEvent（common.KlineUnit及其周期）
 	// common.Trade
 	// common.Depth
+	// common.FundingRate
+	// timerEvent（定时器，instIdIndex无意义）
+	// barCloseEvent（时间bar收盘）
+	// dayChangeEvent（换日，instIdIndex无意义）
 	// 使用时需要做动态类型断言
 	data interface{}
 }
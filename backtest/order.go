@@ -0,0 +1,110 @@
+/*
+- @Author: aztec
+- @Date: 2026-10-16 09:12:40
+- @Description: 回测订单
+- @
+- @Copyright (c) 2024 by aztec, All Rights Reserved.
+*/
+package backtest
+
+import (
+	"time"
+
+	"github.com/aztecqt/qbench/common"
+	"github.com/shopspring/decimal"
+)
+
+// 订单状态
+type OrderStatus int
+
+const (
+	OrderStatus_Open      OrderStatus = iota // 挂单中（包括部分成交）
+	OrderStatus_Filled                       // 完全成交
+	OrderStatus_Cancelled                    // 已撤销（包括部分成交后撤销）
+	OrderStatus_Pending                      // 已提交，尚未到达交易所（模拟延迟时）
+	OrderStatus_Rejected                     // 被拒绝，原因见RejectReason
+)
+
+func (s OrderStatus) String() string {
+	switch s {
+	case OrderStatus_Open:
+		return "open"
+	case OrderStatus_Filled:
+		return "filled"
+	case OrderStatus_Cancelled:
+		return "cancelled"
+	case OrderStatus_Pending:
+		return "pending"
+	case OrderStatus_Rejected:
+		return "rejected"
+	default:
+		return "unknown"
+	}
+}
+
+// 订单被拒绝的原因
+type RejectReason string
+
+const (
+	RejectReason_None                RejectReason = ""
+	RejectReason_InsufficientBalance RejectReason = "insufficient_balance" // 现货余额不足
+	RejectReason_InsufficientMargin  RejectReason = "insufficient_margin"  // 合约可用保证金不足
+	RejectReason_InvalidPrice        RejectReason = "invalid_price"        // 价格无效（按TickSize对齐后不为正）
+	RejectReason_InvalidAmount       RejectReason = "invalid_amount"       // 数量无效（按LotSize对齐后不为正）
+	RejectReason_MinNotional         RejectReason = "min_notional"         // 下单金额低于最小值
+	RejectReason_MaxAmount           RejectReason = "max_amount"           // 下单数量超过最大值
+	RejectReason_PostOnly            RejectReason = "post_only"            // 挂单价格穿过盘口，会立即成交
+)
+
+// 订单
+// SignalMaker生成的订单会驻留在executor中，由后续行情撮合成交
+// SignalTaker生成的订单立即成交，未成交的部分直接撤销
+type Order struct {
+	Id           int64
+	InstId       string
+	PosSide      common.PosSide // 持仓方向，单向持仓的品种为PosSide_Net
+	Price        decimal.Decimal
+	Amount       decimal.Decimal // 下单数量
+	Filled       decimal.Decimal // 已成交数量
+	IsSell       bool
+	Taker        bool
+	Tag          string          // 策略标签，见Context.SetOrderTag。强平单为OrderTag_Liquidation
+	QueueAhead   decimal.Decimal // 前方排队数量，由成交模型维护
+	Status       OrderStatus
+	RejectReason RejectReason
+	CreateTime   time.Time
+	UpdateTime   time.Time
+}
+
+// 剩余未成交数量
+func (o *Order) Remaining() decimal.Decimal {
+	return o.Amount.Sub(o.Filled)
+}
+
+// 是否仍在挂单中
+func (o *Order) IsOpen() bool {
+	return o.Status == OrderStatus_Open
+}
+
+// 成交记录
+type Fill struct {
+	OrderId     int64           `json:"order_id"`
+	InstId      string          `json:"inst_id"`
+	PosSide     common.PosSide  `json:"pos_side"`
+	Time        time.Time       `json:"time"`
+	Price       decimal.Decimal `json:"price"`  // 真实成交价格
+	Amount      decimal.Decimal `json:"amount"` // 真实成交数量
+	IsSell      bool            `json:"is_sell"`
+	Taker       bool            `json:"taker"`
+	Fee         decimal.Decimal `json:"fee"`          // 手续费，正数表示支出
+	FeeCcy      string          `json:"fee_ccy"`      // 手续费币种
+	RealizedPnl decimal.Decimal `json:"realized_pnl"` // 合约平仓盈亏（不含手续费，保证金币种），开仓和现货成交为0
+	StrategyTag string          `json:"strategy_tag"` // 所属订单的策略标签
+}
+
+// 订单事件，三选一
+type orderEvent struct {
+	order       *Order
+	fill        *Fill
+	liquidation *Fill // 强平成交
+}
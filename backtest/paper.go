@@ -0,0 +1,189 @@
+/*
+- @Author: aztec
+- @Date: 2026-10-16 23:51:06
+- @Description: 模拟盘执行器。行情来自实时行情源，成交由executor模拟
+- @
+- @Copyright (c) 2024 by aztec, All Rights Reserved.
+*/
+package backtest
+
+import (
+	"sync"
+	"time"
+
+	"github.com/aztecqt/qbench/common"
+	"github.com/aztecqt/qbench/data/feed"
+	"github.com/aztecqt/qbench/data/local"
+)
+
+// 模拟盘配置
+type PaperConfig struct {
+	Executor ExecutorConfig
+
+	// 以行情自带的时间作为模拟时间，定时器只随行情时间推进，用于对ReplayServer的快速回放做测试
+	// 默认以本地时钟作为模拟时间，定时器按本地时钟触发
+	FeedTime bool
+
+	// 交易品种规则，开启Executor.ValidateOrders时使用
+	// Rules为空时，与回测一样从本地数据目录的instruments/<Ex>.json加载
+	Ex    common.ExName
+	Rules map[string]common.InstrumentRule
+}
+
+// 模拟盘执行器
+// 与回测共用同一套撮合、仓位、资产逻辑，实现同样的Context，驱动同样的策略，区别只在于行情来源
+// 行情源可以是任何实现了feed.Feed的适配器
+type PaperExecutor struct {
+	*Executor
+	feed     feed.Feed
+	feedTime bool
+	ex       common.ExName
+	rules    map[string]common.InstrumentRule
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// localDataPath只用于加载交易品种规则
+func NewPaperExecutor(localDataPath string, cfg PaperConfig, f feed.Feed) *PaperExecutor {
+	local.Init(localDataPath)
+	cfg.Executor.HideProgress = true
+	return &PaperExecutor{
+		Executor: newExecutor(cfg.Executor),
+		feed:     f,
+		feedTime: cfg.FeedTime,
+		ex:       cfg.Ex,
+		rules:    cfg.Rules,
+		stop:     make(chan struct{})}
+}
+
+// 运行策略，直到行情源结束或者调用Stop
+// 结果的统计方式与回测相同
+func (p *PaperExecutor) Run(s Strategy) (*BacktestResult, bool) {
+	e := p.Executor
+	if !e.initMarketInfo(s.MarketInfoRequired()) {
+		return nil, false
+	}
+
+	if p.rules != nil {
+		e.rules = p.rules
+	} else {
+		e.loadInstrumentRules(p.ex)
+	}
+
+	if !p.feed.Start(e.instIds) {
+		return nil, false
+	}
+	defer p.feed.Close()
+
+	// 按本地时钟运行时立即启动策略，按行情时间运行时在收到第一条行情时启动
+	started := false
+	begin := func(t time.Time) {
+		started = true
+		e.begin(s, t)
+		common.LogNormal(logPrefix, "paper trading started: %s", s.Class())
+	}
+
+	if !p.feedTime {
+		begin(time.Now())
+	}
+
+	// 按本地时钟运行时，用于等待下一个定时事件（定时器、时间bar收盘）
+	// 按行情时间运行时，定时事件只在收到行情时处理
+	timer := time.NewTimer(time.Hour)
+	var timerC <-chan time.Time
+	if !p.feedTime {
+		timerC = timer.C
+	}
+
+	updates := p.feed.Updates()
+	for running := true; running; {
+		if !p.feedTime {
+			p.resetTimer(timer)
+		}
+
+		select {
+		case u, ok := <-updates:
+			if !ok {
+				running = false
+			} else if miu, ok := p.toUnit(u); ok {
+				if !started {
+					begin(miu.time)
+				}
+				p.runDue(s, miu.time)
+				e.step(s, miu)
+			}
+		case <-timerC:
+			p.runDue(s, time.Now())
+		case <-p.stop:
+			running = false
+		}
+	}
+	timer.Stop()
+
+	if !started {
+		begin(time.Now())
+	}
+	common.LogNormal(logPrefix, "paper trading stopped: %s", s.Class())
+	return e.finish(s), true
+}
+
+// 停止运行，可以在其他goroutine中调用
+func (p *PaperExecutor) Stop() {
+	p.stopOnce.Do(func() { close(p.stop) })
+}
+
+// 把实时行情转换为行情单元
+// 模拟时间不会倒退，早于当前时间的行情按当前时间处理
+func (p *PaperExecutor) toUnit(u feed.Update) (marketInfoUnit, bool) {
+	index, ok := p.instIdIndexs[u.InstId]
+	if !ok {
+		return marketInfoUnit{}, false
+	}
+
+	t := time.Now()
+	if p.feedTime {
+		t = u.Time
+	}
+	if t.Before(p.Time) {
+		t = p.Time
+	}
+
+	data := u.Data
+	if k, ok := data.(feed.Kline); ok {
+		data = klineEvent{intervalSec: k.IntervalSec, k: k.Unit}
+	}
+
+	return marketInfoUnit{instIdIndex: index, time: t, data: data}, true
+}
+
+// 处理所有到期的定时事件
+func (p *PaperExecutor) runDue(s Strategy, t time.Time) {
+	for {
+		next, ok := p.stream.peekTime()
+		if !ok || next.After(t) {
+			return
+		}
+
+		miu, _ := p.stream.next()
+		if miu.time.Before(p.Time) {
+			miu.time = p.Time
+		}
+		p.step(s, miu)
+	}
+}
+
+// 按本地时钟等待下一个定时事件
+func (p *PaperExecutor) resetTimer(timer *time.Timer) {
+	if !timer.Stop() {
+		select {
+		case <-timer.C:
+		default:
+		}
+	}
+
+	wait := time.Hour
+	if t, ok := p.stream.peekTime(); ok {
+		wait = max(time.Until(t), 0)
+	}
+	timer.Reset(wait)
+}
@@ -0,0 +1,68 @@
+/*
+- @Author: aztec
+- @Date: 2026-10-17 00:26:15
+- @Description: 策略注册表。按名称创建策略，用于从配置文件构造策略
+- @
+- @Copyright (c) 2024 by aztec, All Rights Reserved.
+*/
+package backtest
+
+import (
+	"encoding/json"
+	"slices"
+	"sync"
+
+	"github.com/aztecqt/qbench/common"
+)
+
+// 策略工厂。params为配置文件中该策略的参数（原始json），解析失败时返回false
+type StrategyFactory func(params json.RawMessage) (Strategy, bool)
+
+var (
+	strategyFactories   = map[string]StrategyFactory{}
+	strategyFactoriesMu sync.RWMutex
+)
+
+// 注册策略，一般在策略所在包的init中调用
+// 重复注册同一名称时，后注册的覆盖先注册的
+func RegisterStrategy(name string, factory StrategyFactory) {
+	strategyFactoriesMu.Lock()
+	defer strategyFactoriesMu.Unlock()
+
+	if _, ok := strategyFactories[name]; ok {
+		common.LogError(logPrefix, "strategy %s registered more than once", name)
+	}
+	strategyFactories[name] = factory
+}
+
+// 按名称创建策略
+func NewStrategy(name string, params json.RawMessage) (Strategy, bool) {
+	strategyFactoriesMu.RLock()
+	factory, ok := strategyFactories[name]
+	strategyFactoriesMu.RUnlock()
+
+	if !ok {
+		common.LogError(logPrefix, "strategy %s not registered", name)
+		return nil, false
+	}
+
+	if s, ok := factory(params); ok {
+		return s, true
+	} else {
+		common.LogError(logPrefix, "create strategy %s failed, params: %s", name, string(params))
+		return nil, false
+	}
+}
+
+// 已注册的策略名称（按字母顺序）
+func RegisteredStrategies() []string {
+	strategyFactoriesMu.RLock()
+	defer strategyFactoriesMu.RUnlock()
+
+	names := make([]string, 0, len(strategyFactories))
+	for name := range strategyFactories {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
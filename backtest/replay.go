@@ -0,0 +1,108 @@
+/*
+- @Author: aztec
+- @Date: 2026-10-16 17:08:25
+- @Description: 预加载的行情数据，用于同一段行情的多次回测
+- @
+- @Copyright (c) 2024 by aztec, All Rights Reserved.
+*/
+package backtest
+
+import (
+	"slices"
+	"time"
+
+	"github.com/aztecqt/dagger/util/terminal"
+	"github.com/aztecqt/qbench/common"
+	"github.com/aztecqt/qbench/data/feed"
+)
+
+// 预加载的行情数据
+// 一次性把行情按时间顺序读入内存，之后可以被多个executor同时回放
+// 加载完成后只读，回放时每个executor有自己的读取位置，互不影响
+// 注意：盘口等数据中的切片在各executor之间是共享的，策略不应修改收到的行情
+type ReplayData struct {
+	localDataPath string
+	ex            common.ExName
+	t0, t1        time.Time
+	cfg           MarketInfoLoadingConfig
+	units         []marketInfoUnit
+}
+
+// 加载行情数据
+func LoadReplayData(localDataPath string, ex common.ExName, t0, t1 time.Time, cfg MarketInfoLoadingConfig) (*ReplayData, bool) {
+	e := NewExecutor(localDataPath, ExecutorConfigDefault())
+	if !e.loadMarketInfo(ex, t0, t1, cfg) {
+		return nil, false
+	}
+
+	rd := &ReplayData{localDataPath: localDataPath, ex: ex, t0: t0, t1: t1, cfg: cfg}
+	tracker := terminal.GenTrackerWithHardwareInfo("行情加载", t1.Sub(t0).Seconds(), 30, true, false, true, true, true)
+	for {
+		u, ok := e.stream.next()
+		if !ok {
+			break
+		}
+
+		rd.units = append(rd.units, u)
+		tracker.SetValue(u.time.Sub(t0).Seconds())
+	}
+
+	tracker.MarkAsDone()
+	time.Sleep(time.Millisecond * 100)
+	return rd, true
+}
+
+// 行情数量
+func (rd *ReplayData) Len() int {
+	return len(rd.units)
+}
+
+// 截取[t0, t1)时间段内的行情，与原数据共享内存
+func (rd *ReplayData) Slice(t0, t1 time.Time) *ReplayData {
+	fnCmp := func(u marketInfoUnit, t time.Time) int { return u.time.Compare(t) }
+	i0, _ := slices.BinarySearchFunc(rd.units, t0, fnCmp)
+	i1, _ := slices.BinarySearchFunc(rd.units, t1, fnCmp)
+	sub := *rd
+	sub.t0 = t0
+	sub.t1 = t1
+	sub.units = rd.units[i0:i1]
+	return &sub
+}
+
+// 时间范围
+func (rd *ReplayData) TimeRange() (time.Time, time.Time) {
+	return rd.t0, rd.t1
+}
+
+// 转换为实时行情序列，用于feed.ReplayServer回放
+func (rd *ReplayData) FeedUpdates() []feed.Update {
+	updates := make([]feed.Update, 0, len(rd.units))
+	for _, u := range rd.units {
+		data := u.data
+		if ke, ok := data.(klineEvent); ok {
+			data = feed.Kline{IntervalSec: ke.intervalSec, Unit: ke.k}
+		}
+		updates = append(updates, feed.Update{InstId: rd.cfg.InstIds[u.instIdIndex], Time: u.time, Data: data})
+	}
+	return updates
+}
+
+func (rd *ReplayData) newSource() marketInfoSource {
+	return &sliceSource{units: rd.units}
+}
+
+// 从内存中的行情序列读取的行情源
+type sliceSource struct {
+	units []marketInfoUnit
+	idx   int
+}
+
+func (s *sliceSource) next() (marketInfoUnit, bool) {
+	if s.idx < len(s.units) {
+		u := s.units[s.idx]
+		s.idx++
+		return u, true
+	} else {
+		return marketInfoUnit{}, false
+	}
+}
@@ -0,0 +1,286 @@
+/*
+- @Author: aztec
+- @Date: 2026-10-16 15:12:06
+- @Description: 回测结果与绩效统计
+- @
+- @Copyright (c) 2024 by aztec, All Rights Reserved.
+*/
+package backtest
+
+import (
+	"encoding/json"
+	"math"
+	"time"
+
+	"github.com/aztecqt/qbench/common"
+)
+
+// 一年的时长，数字货币市场全年无休，按365天计
+const yearDuration = time.Hour * 24 * 365
+
+// 年化收益率的最短统计时长。时长太短时，复利年化的指数过大，几天的收益会被放大成天文数字甚至溢出
+// 不足30天时年化收益率和卡玛比率为0，按这两个指标优化参数时，回测区间（或walk-forward的窗口）应不短于30天
+const minAnnualizeSpan = time.Hour * 24 * 30
+
+// 单位净值采样点
+type NavPoint struct {
+	Time time.Time `json:"time"`
+	Nav  float64   `json:"nav"`
+}
+
+// 基于净值序列的绩效指标
+// 收益率、波动率都是年化之后的值，夏普、索提诺不扣除无风险利率
+type PerformanceMetrics struct {
+	TotalReturn            float64 `json:"total_return"`              // 总收益率
+	AnnualReturn           float64 `json:"annual_return"`             // 年化收益率（复利）
+	AnnualVolatility       float64 `json:"annual_volatility"`         // 年化波动率
+	Sharpe                 float64 `json:"sharpe"`                    // 夏普比率
+	Sortino                float64 `json:"sortino"`                   // 索提诺比率
+	Calmar                 float64 `json:"calmar"`                    // 卡玛比率（年化收益率/最大回撤）
+	MaxDrawdown            float64 `json:"max_drawdown"`              // 最大回撤（正数）
+	MaxDrawdownDurationSec float64 `json:"max_drawdown_duration_sec"` // 最长回撤持续时间（从前高到收复前高）
+}
+
+// 单个品种的交易统计
+type InstrumentStats struct {
+	Trades   int     `json:"trades"`   // 成交笔数
+	Volume   float64 `json:"volume"`   // 成交数量
+	Turnover float64 `json:"turnover"` // 成交额（计价币种）
+}
+
+// 回测结果
+type BacktestResult struct {
+	Strategy     string         `json:"strategy"`
+	StartTime    time.Time      `json:"start_time"`
+	EndTime      time.Time      `json:"end_time"`
+	ValuationCcy string         `json:"valuation_ccy"` // 估值币种
+	Navs         []NavPoint     `json:"navs"`
+	Breakdown    []CcyBreakdown `json:"breakdown"` // 分币种资产情况，与Navs同时采样
+
+	PerformanceMetrics
+
+	Instruments map[string]*InstrumentStats `json:"instruments"` // 各品种的交易统计
+	Fees        map[string]float64          `json:"fees"`        // 各币种的手续费支出
+	Interest    map[string]float64          `json:"interest"`    // 各币种的借贷利息支出（现货杠杆）
+
+	// 合约平仓统计，来自各仓位的ProfitRecords，每次完全平仓算一笔
+	// U本位和币本位合约的盈亏币种不同，平均盈亏按保证金币种分别统计
+	ClosedTrades int                `json:"closed_trades"`
+	WinRate      float64            `json:"win_rate"`
+	AvgWin       map[string]float64 `json:"avg_win"`
+	AvgLoss      map[string]float64 `json:"avg_loss"` // 负数
+}
+
+// 可用于排序、优化的指标名称，与json字段名一致
+const (
+	Metric_TotalReturn         = "total_return"
+	Metric_AnnualReturn        = "annual_return"
+	Metric_AnnualVolatility    = "annual_volatility"
+	Metric_Sharpe              = "sharpe"
+	Metric_Sortino             = "sortino"
+	Metric_Calmar              = "calmar"
+	Metric_MaxDrawdown         = "max_drawdown"
+	Metric_MaxDrawdownDuration = "max_drawdown_duration_sec"
+	Metric_WinRate             = "win_rate"
+)
+
+// 按名称读取绩效指标
+func (pm PerformanceMetrics) Metric(name string) (float64, bool) {
+	switch name {
+	case Metric_TotalReturn:
+		return pm.TotalReturn, true
+	case Metric_AnnualReturn:
+		return pm.AnnualReturn, true
+	case Metric_AnnualVolatility:
+		return pm.AnnualVolatility, true
+	case Metric_Sharpe:
+		return pm.Sharpe, true
+	case Metric_Sortino:
+		return pm.Sortino, true
+	case Metric_Calmar:
+		return pm.Calmar, true
+	case Metric_MaxDrawdown:
+		return pm.MaxDrawdown, true
+	case Metric_MaxDrawdownDuration:
+		return pm.MaxDrawdownDurationSec, true
+	default:
+		return 0, false
+	}
+}
+
+// 按名称读取指标，包含绩效指标和交易统计指标
+func (r *BacktestResult) Metric(name string) (float64, bool) {
+	if name == Metric_WinRate {
+		return r.WinRate, true
+	} else {
+		return r.PerformanceMetrics.Metric(name)
+	}
+}
+
+// 指标是否越大越好（波动率、回撤类指标越小越好）
+func MetricHigherIsBetter(name string) bool {
+	switch name {
+	case Metric_AnnualVolatility, Metric_MaxDrawdown, Metric_MaxDrawdownDuration:
+		return false
+	default:
+		return true
+	}
+}
+
+// 根据净值序列计算绩效指标
+// 序列至少需要两个点，否则所有指标为0
+// 时长不足minAnnualizeSpan时不计算年化收益率（及卡玛比率），无法得出有限值的指标一律为0
+func CalcPerformance(navs []NavPoint) PerformanceMetrics {
+	pm := PerformanceMetrics{}
+	if len(navs) < 2 || navs[0].Nav <= 0 {
+		return pm
+	}
+
+	first := navs[0]
+	last := navs[len(navs)-1]
+	span := last.Time.Sub(first.Time)
+	pm.TotalReturn = last.Nav/first.Nav - 1
+
+	if span >= minAnnualizeSpan && last.Nav > 0 {
+		pm.AnnualReturn = finiteOrZero(math.Pow(last.Nav/first.Nav, float64(yearDuration)/float64(span)) - 1)
+	}
+
+	// 逐期收益率
+	returns := make([]float64, 0, len(navs)-1)
+	for i := 1; i < len(navs); i++ {
+		if navs[i-1].Nav > 0 {
+			returns = append(returns, navs[i].Nav/navs[i-1].Nav-1)
+		}
+	}
+
+	if len(returns) > 0 && span > 0 {
+		// 按平均采样间隔折算每年的期数
+		periodsPerYear := float64(yearDuration) / (float64(span) / float64(len(navs)-1))
+
+		mean := 0.0
+		for _, r := range returns {
+			mean += r
+		}
+		mean /= float64(len(returns))
+
+		variance := 0.0
+		downside := 0.0
+		for _, r := range returns {
+			variance += (r - mean) * (r - mean)
+			if r < 0 {
+				downside += r * r
+			}
+		}
+		variance /= float64(len(returns))
+		downside /= float64(len(returns))
+
+		pm.AnnualVolatility = finiteOrZero(math.Sqrt(variance * periodsPerYear))
+		if pm.AnnualVolatility > 0 {
+			pm.Sharpe = finiteOrZero(mean * periodsPerYear / pm.AnnualVolatility)
+		}
+
+		if downsideVol := math.Sqrt(downside * periodsPerYear); downsideVol > 0 {
+			pm.Sortino = finiteOrZero(mean * periodsPerYear / downsideVol)
+		}
+	}
+
+	// 最大回撤及持续时间
+	peak := first
+	inDrawdown := false
+	for _, p := range navs {
+		if p.Nav >= peak.Nav {
+			if inDrawdown {
+				if d := p.Time.Sub(peak.Time).Seconds(); d > pm.MaxDrawdownDurationSec {
+					pm.MaxDrawdownDurationSec = d
+				}
+				inDrawdown = false
+			}
+			peak = p
+		} else {
+			inDrawdown = true
+			if dd := (peak.Nav - p.Nav) / peak.Nav; dd > pm.MaxDrawdown {
+				pm.MaxDrawdown = dd
+			}
+		}
+	}
+
+	// 结束时仍未收复前高
+	if inDrawdown {
+		if d := last.Time.Sub(peak.Time).Seconds(); d > pm.MaxDrawdownDurationSec {
+			pm.MaxDrawdownDurationSec = d
+		}
+	}
+
+	if pm.MaxDrawdown > 0 {
+		pm.Calmar = finiteOrZero(pm.AnnualReturn / pm.MaxDrawdown)
+	}
+
+	return pm
+}
+
+// 无穷大和NaN无法序列化为json，按0处理
+func finiteOrZero(v float64) float64 {
+	if math.IsInf(v, 0) || math.IsNaN(v) {
+		return 0
+	} else {
+		return v
+	}
+}
+
+// 根据平仓记录计算胜率和平均盈亏
+// records为保证金币种->平仓记录。胜率不区分币种，平均盈亏按币种分别计算
+func (r *BacktestResult) calcWinLoss(records map[string][]common.ProfitRecord) {
+	r.ClosedTrades = 0
+	r.AvgWin = map[string]float64{}
+	r.AvgLoss = map[string]float64{}
+
+	totalWins := 0
+	for ccy, prs := range records {
+		wins, losses := 0, 0
+		sumWin, sumLoss := 0.0, 0.0
+		for _, pr := range prs {
+			p := pr.Profit.InexactFloat64()
+			if p > 0 {
+				wins++
+				sumWin += p
+			} else if p < 0 {
+				losses++
+				sumLoss += p
+			}
+		}
+
+		r.ClosedTrades += len(prs)
+		totalWins += wins
+
+		if wins > 0 {
+			r.AvgWin[ccy] = sumWin / float64(wins)
+		}
+
+		if losses > 0 {
+			r.AvgLoss[ccy] = sumLoss / float64(losses)
+		}
+	}
+
+	if r.ClosedTrades > 0 {
+		r.WinRate = float64(totalWins) / float64(r.ClosedTrades)
+	}
+}
+
+// 序列化为json
+func (r *BacktestResult) ToJSON() ([]byte, bool) {
+	if b, err := json.MarshalIndent(r, "", "  "); err == nil {
+		return b, true
+	} else {
+		common.LogError(logPrefix, "marshal backtest result failed: %s", err.Error())
+		return nil, false
+	}
+}
+
+// 保存为json文件
+func (r *BacktestResult) SaveJSON(path string) bool {
+	if b, ok := r.ToJSON(); ok {
+		return common.SaveFile(logPrefix, path, b)
+	} else {
+		return false
+	}
+}
@@ -0,0 +1,121 @@
+package backtest
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// 从t0开始，每隔interval一个净值点
+func testNavs(t0 time.Time, interval time.Duration, values ...float64) []NavPoint {
+	navs := make([]NavPoint, 0, len(values))
+	for i, v := range values {
+		navs = append(navs, NavPoint{Time: t0.Add(interval * time.Duration(i)), Nav: v})
+	}
+	return navs
+}
+
+func TestCalcPerformance(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	day := time.Hour * 24
+
+	cases := []struct {
+		name   string
+		navs   []NavPoint
+		expect PerformanceMetrics
+	}{
+		{"empty", nil, PerformanceMetrics{}},
+		{"single point", testNavs(t0, day, 100), PerformanceMetrics{}},
+		{"zero initial nav", testNavs(t0, day, 0, 100), PerformanceMetrics{}},
+		{
+			"steady growth",
+			testNavs(t0, day, 100, 110, 121),
+			PerformanceMetrics{TotalReturn: 0.21},
+		},
+		{
+			"ends in drawdown",
+			testNavs(t0, day, 100, 110, 99),
+			PerformanceMetrics{
+				TotalReturn:            -0.01,
+				AnnualVolatility:       1.910497317,
+				MaxDrawdown:            0.1,
+				MaxDrawdownDurationSec: day.Seconds(),
+			},
+		},
+		{
+			"recovered drawdown",
+			testNavs(t0, day, 100, 120, 90, 130, 110),
+			PerformanceMetrics{
+				TotalReturn:            0.1,
+				AnnualVolatility:       5.311969524,
+				Sharpe:                 4.133042164,
+				Sortino:                7.829505925,
+				MaxDrawdown:            0.25,
+				MaxDrawdownDurationSec: (day * 2).Seconds(),
+			},
+		},
+		{
+			"short span",
+			testNavs(t0, time.Minute*30, 100, 105, 110),
+			PerformanceMetrics{TotalReturn: 0.1},
+		},
+		{
+			// 不足30天不计算年化收益率和卡玛比率
+			"below annualize floor",
+			testNavs(t0, day*29, 100, 200),
+			PerformanceMetrics{TotalReturn: 1},
+		},
+		{
+			"one year",
+			testNavs(t0, day*365, 100, 110),
+			PerformanceMetrics{TotalReturn: 0.1, AnnualReturn: 0.1},
+		},
+		{
+			"one year with drawdown",
+			testNavs(t0, day*365/2, 100, 80, 121),
+			PerformanceMetrics{
+				TotalReturn:            0.21,
+				AnnualReturn:           0.21,
+				Calmar:                 1.05,
+				MaxDrawdown:            0.2,
+				MaxDrawdownDurationSec: (day * 365).Seconds(),
+			},
+		},
+		{
+			// 年化收益率溢出为无穷大时按0处理
+			"annual return overflow",
+			testNavs(t0, day*30, 100, 1e30),
+			PerformanceMetrics{TotalReturn: 1e28 - 1},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := CalcPerformance(c.navs)
+			check := func(field string, expect, actual float64) {
+				if math.IsNaN(actual) || math.IsInf(actual, 0) {
+					t.Errorf("%s: not finite: %v", field, actual)
+				} else if math.Abs(expect-actual) > math.Max(1e-6, math.Abs(expect)*1e-8) {
+					t.Errorf("%s: expect %v, got %v", field, expect, actual)
+				}
+			}
+
+			check("total_return", c.expect.TotalReturn, got.TotalReturn)
+			check("annual_return", c.expect.AnnualReturn, got.AnnualReturn)
+			check("max_drawdown", c.expect.MaxDrawdown, got.MaxDrawdown)
+			check("max_drawdown_duration_sec", c.expect.MaxDrawdownDurationSec, got.MaxDrawdownDurationSec)
+			check("calmar", c.expect.Calmar, got.Calmar)
+
+			// 波动率相关指标只在设置了期望值时精确比较，其余情况只要求有限
+			if c.expect.AnnualVolatility != 0 {
+				check("annual_volatility", c.expect.AnnualVolatility, got.AnnualVolatility)
+				check("sharpe", c.expect.Sharpe, got.Sharpe)
+				check("sortino", c.expect.Sortino, got.Sortino)
+			} else {
+				check("annual_volatility", got.AnnualVolatility, got.AnnualVolatility)
+				check("sharpe", got.Sharpe, got.Sharpe)
+				check("sortino", got.Sortino, got.Sortino)
+			}
+		})
+	}
+}
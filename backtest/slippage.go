@@ -0,0 +1,167 @@
+/*
+- @Author: aztec
+- @Date: 2026-10-16 20:47:03
+- @Description: 吃单滑点模型
+- @
+- @Copyright (c) 2024 by aztec, All Rights Reserved.
+*/
+package backtest
+
+import (
+	"math"
+	"time"
+
+	"github.com/aztecqt/qbench/common"
+	"github.com/shopspring/decimal"
+)
+
+// 品种近期的市场统计，供滑点模型使用
+type MarketStats struct {
+	Volatility float64       // 统计窗口内的分钟波动率：对数收益率平方和除以经过的分钟数再开方，数据间隔不是1分钟时也按实际间隔折算
+	Volume     float64       // 统计窗口内的成交量
+	Window     time.Duration // 统计窗口长度
+}
+
+// 滑点模型
+// 没有加载真实盘口时，吃单按策略给出的价格成交，过于乐观。滑点模型在此价格上叠加一个不利的偏移
+// 返回偏移比例（非负），买入时成交价格上浮，卖出时下调
+type SlippageModel interface {
+	Slippage(instId string, price, amount decimal.Decimal, isSell bool, stats MarketStats) float64
+}
+
+// 内置的滑点模型名称，用于ExecutorConfig
+const (
+	Slippage_None       = ""
+	Slippage_FixedBps   = "fixed_bps"   // 固定滑点，SlippageBps个基点
+	Slippage_Volatility = "volatility"  // 滑点 = SlippageK * 分钟波动率
+	Slippage_SqrtImpact = "sqrt_impact" // 平方根冲击：滑点 = SlippageK * 窗口波动率 * sqrt(下单数量/窗口成交量)
+)
+
+func newSlippageModel(cfg ExecutorConfig) SlippageModel {
+	switch cfg.Slippage {
+	case Slippage_None:
+		return nil
+	case Slippage_FixedBps:
+		return &FixedBpsSlippage{Bps: cfg.SlippageBps}
+	case Slippage_Volatility:
+		return &VolatilitySlippage{K: cfg.SlippageK}
+	case Slippage_SqrtImpact:
+		return &SqrtImpactSlippage{K: cfg.SlippageK}
+	default:
+		common.LogError(logPrefix, "unknown slippage model %s, no slippage will be applied", cfg.Slippage)
+		return nil
+	}
+}
+
+// 固定滑点
+type FixedBpsSlippage struct {
+	Bps float64
+}
+
+func (m *FixedBpsSlippage) Slippage(instId string, price, amount decimal.Decimal, isSell bool, stats MarketStats) float64 {
+	return math.Max(m.Bps, 0) / 10000
+}
+
+// 与波动率成正比的滑点
+type VolatilitySlippage struct {
+	K float64
+}
+
+func (m *VolatilitySlippage) Slippage(instId string, price, amount decimal.Decimal, isSell bool, stats MarketStats) float64 {
+	return math.Max(m.K*stats.Volatility, 0)
+}
+
+// 平方根市场冲击模型
+// 冲击 = K * σ * sqrt(Q/V)，σ为统计窗口内的波动率，Q为下单数量，V为统计窗口内的成交量
+// 没有成交量数据时不产生冲击
+type SqrtImpactSlippage struct {
+	K float64
+}
+
+func (m *SqrtImpactSlippage) Slippage(instId string, price, amount decimal.Decimal, isSell bool, stats MarketStats) float64 {
+	if stats.Volume <= 0 {
+		return 0
+	}
+
+	sigma := stats.Volatility * math.Sqrt(stats.Window.Minutes())
+	return math.Max(m.K*sigma*math.Sqrt(amount.InexactFloat64()/stats.Volume), 0)
+}
+
+// 按分钟聚合的价格和成交量
+type minuteStat struct {
+	time   time.Time
+	close  float64
+	volume float64
+}
+
+// 滚动窗口内的市场统计
+type marketStatsTracker struct {
+	window  time.Duration
+	minutes []minuteStat
+}
+
+func newMarketStatsTracker(window time.Duration) *marketStatsTracker {
+	return &marketStatsTracker{window: window}
+}
+
+// 取当前分钟的统计单元，并丢弃窗口之外的数据
+// 窗口开始之前的最后一个点保留下来，作为窗口内第一个收益率的起点。数据间隔大于窗口时（如1小时k线），也至少有一个收益率
+func (m *marketStatsTracker) current(t time.Time) *minuteStat {
+	tm := t.Truncate(time.Minute)
+	if n := len(m.minutes); n == 0 || m.minutes[n-1].time.Before(tm) {
+		lastClose := 0.0
+		if n > 0 {
+			lastClose = m.minutes[n-1].close
+		}
+		m.minutes = append(m.minutes, minuteStat{time: tm, close: lastClose})
+	}
+
+	i := 0
+	for i < len(m.minutes)-2 && !m.minutes[i+1].time.After(tm.Add(-m.window)) {
+		i++
+	}
+	m.minutes = m.minutes[i:]
+
+	return &m.minutes[len(m.minutes)-1]
+}
+
+func (m *marketStatsTracker) onPrice(t time.Time, price decimal.Decimal) {
+	m.current(t).close = price.InexactFloat64()
+}
+
+func (m *marketStatsTracker) onVolume(t time.Time, volume decimal.Decimal) {
+	m.current(t).volume += volume.InexactFloat64()
+}
+
+// 统计窗口内的波动率和成交量
+// 相邻两点之间可能相隔多分钟（如5分钟k线），收益率的方差按经过的分钟数折算成每分钟的方差
+func (m *marketStatsTracker) stats() MarketStats {
+	ms := MarketStats{Window: m.window}
+	if len(m.minutes) == 0 {
+		return ms
+	}
+
+	windowStart := m.minutes[len(m.minutes)-1].time.Add(-m.window)
+	sumSq, minutes := 0.0, 0.0
+	for i, st := range m.minutes {
+		if st.time.After(windowStart) {
+			ms.Volume += st.volume
+		}
+
+		if i == 0 {
+			continue
+		}
+
+		if prev := m.minutes[i-1]; prev.close > 0 && st.close > 0 {
+			r := math.Log(st.close / prev.close)
+			sumSq += r * r
+			minutes += st.time.Sub(prev.time).Minutes()
+		}
+	}
+
+	if minutes > 0 {
+		ms.Volatility = math.Sqrt(sumSq / minutes)
+	}
+
+	return ms
+}
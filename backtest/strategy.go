@@ -12,30 +12,60 @@ import (
 
 	"github.com/aztecqt/dagger/util/datavisual"
 	"github.com/aztecqt/qbench/common"
+	"github.com/aztecqt/qbench/data/bars"
 	"github.com/shopspring/decimal"
 )
 
 // 策略
 // 策略运行在一个上下文（context）环境中，这个context应该能提供资产、仓位、订单等数据的查询，同时能接受交易信号）
 // 策略接受行情数据驱动后，结合上下文，给出交易信号
-type strategy interface {
+// 具体策略可以嵌入BaseStrategy，只实现自己关心的回调
+type Strategy interface {
 	// 基本信息
 	Class() string
 
 	// 对行情的需求
 	MarketInfoRequired() MarketInfoLoadingConfig
 
+	// 生命周期
+	// OnStart在行情准备完毕、第一个行情单元之前调用，此时还没有价格和盘口，下的单在之后的行情中执行
+	// OnEnd在行情结束后、统计结果前调用，此时下的单不会再被执行，需要平仓时使用ExecutorConfig.FlattenOnEnd
+	// OnDayChange在每天零点调用，day为新一天的日期
+	OnStart(c Context)
+	OnEnd(c Context)
+	OnDayChange(day time.Time, c Context)
+
 	// 行情驱动
 	OnTicker(instId string, t common.Ticker, c Context)
 	OnDepth(instId string, d common.Depth, c Context)
 	OnTrade(instId string, t common.Trade, c Context)
-	OnKlineUnit(instId string, k common.KlineUnit, c Context)
+	OnKlineUnit(instId string, intervalSec int, k common.KlineUnit, c Context) // intervalSec为k线周期，k.Time为开盘时间
 	OnLiquidation(instId string, t common.Trade, c Context)
 
+	// 由逐笔成交合成的bar（见MarketInfoLoadingConfig.Bars），在bar完成时推送
+	OnBar(instId string, spec bars.Spec, b bars.Bar, c Context)
+
+	// 定时器触发。id为SetTimer/SetInterval返回的定时器id，tag为设置时传入的标签
+	OnTimer(id int64, tag string, c Context)
+
+	// 资金费结算。payment为本次结算的资金费（正数表示收入），无持仓时为0
+	OnFunding(instId string, f common.FundingRate, payment decimal.Decimal, c Context)
+
+	// 订单驱动
+	// 订单状态发生变化时（下单、成交、撤销、修改、拒绝）推送订单快照，被拒绝的订单带有RejectReason
+	// 发生成交时推送真实的成交价格、数量和手续费
+	OnOrderUpdate(o Order, c Context)
+	OnFill(f Fill, c Context)
+
+	// 仓位被强平。f为强平成交（按标记价格吃单平仓）
+	OnPositionLiquidated(f Fill, c Context)
+
 	// 可视化数据的收集与保存
+	// 写入dgDefault和Context.NewVisualGroup创建的数据组的数据，由executor保存，并会出现在html报告中
+	// 策略自己创建、在OnVisualDataSaving中保存的datavisual.DataGroup只能在CommonDataViewer中查看
 	OnVisualDataInit(intervalMs int64, c Context)
-	OnVisualDataRefeshing(dgDefault *datavisual.DataGroup, c Context)
-	OnVisualDataSaving(rootDir string, lcDefault **datavisual.LayoutConfig, c Context)
+	OnVisualDataRefeshing(dgDefault *VisualGroup, c Context)
+	OnVisualDataSaving(rootDir string, lcDefault **datavisual.LayoutConfig, c Context) // lcDefault可以不设置，此时不保存默认布局
 }
 
 // 策略上下文
@@ -44,9 +74,50 @@ type Context interface {
 	GetTime() time.Time
 	GetBalance(ccy string) (decimal.Decimal, bool)
 	GetPosition(instId string) (amount decimal.Decimal, avgPrice decimal.Decimal)
+	GetLiquidationPrice(instId string) (decimal.Decimal, bool)
+	GetLiability(ccy string) decimal.Decimal // 现货杠杆账户的负债（SpotOversell=margin时）
+
+	// 双向持仓（对冲模式）品种的数据访问，side为PosSide_Long/PosSide_Short
+	GetPositionOfSide(instId string, side common.PosSide) (amount decimal.Decimal, avgPrice decimal.Decimal)
+	GetLiquidationPriceOfSide(instId string, side common.PosSide) (decimal.Decimal, bool)
 	GetLatestPrice(instId string) (decimal.Decimal, bool)
 	GetDepth(instId string) (common.Depth, bool)
+	GetOpenOrders(instId string) []Order
+
+	// 设置合约杠杆倍数（逐仓）
+	SetLeverage(instId string, leverage decimal.Decimal)
+
+	// 创建一个可视化数据组（同名时返回已有的数据组），由executor在回测结束时保存到可视化目录下的同名子目录
+	NewVisualGroup(name string) *VisualGroup
+
+	// 设置后续下单使用的策略标签，会记录在订单和成交中。空字符串表示使用策略的Class()
+	SetOrderTag(tag string)
 
 	// 交易信号输出
-	SignalTaker(instId string, price, amount decimal.Decimal, isSell bool)
+	// 吃单信号。按盘口立即成交，未成交的部分直接撤销。返回订单id
+	SignalTaker(instId string, price, amount decimal.Decimal, isSell bool) int64
+
+	// 挂单信号。订单驻留在executor中，后续行情（盘口/成交）满足条件时按挂单价格成交，收取maker手续费
+	// 挂单为只做maker（post-only）：到达交易所时价格穿过盘口（买价不低于卖一，或卖价不高于买一）会被拒绝，原因为RejectReason_PostOnly
+	// 返回订单id
+	SignalMaker(instId string, price, amount decimal.Decimal, isSell bool) int64
+
+	// 双向持仓（对冲模式）品种的交易信号，需要指明持仓方向（PosSide_Long/PosSide_Short）
+	// 多头买入开仓、卖出平仓；空头卖出开仓、买入平仓。平仓数量超过仓位的部分不会成交
+	// 持仓方向与品种的持仓模式不匹配时，信号被忽略，返回0
+	SignalTakerOfSide(instId string, side common.PosSide, price, amount decimal.Decimal, isSell bool) int64
+	SignalMakerOfSide(instId string, side common.PosSide, price, amount decimal.Decimal, isSell bool) int64
+
+	// 定时器
+	// SetTimer在模拟时间at触发一次，SetInterval在interval的整数倍时刻（如每个整点）周期触发，通过OnTimer回调
+	// 与行情时间相同时，定时器在行情之后触发。返回定时器id
+	SetTimer(at time.Time, tag string) int64
+	SetInterval(interval time.Duration, tag string) int64
+	CancelTimer(id int64) bool
+
+	// 撤销挂单
+	CancelOrder(id int64) bool
+
+	// 修改挂单的价格和数量（amount为修改后的下单总数量）。修改后的价格穿过盘口时修改失败
+	AmendOrder(id int64, price, amount decimal.Decimal) bool
 }
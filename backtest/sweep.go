@@ -0,0 +1,303 @@
+/*
+- @Author: aztec
+- @Date: 2026-10-16 17:31:54
+- @Description: 参数扫描。同一段行情上，用不同的参数组合并行回测，按指标排序
+- @
+- @Copyright (c) 2024 by aztec, All Rights Reserved.
+*/
+package backtest
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aztecqt/dagger/util/terminal"
+	"github.com/aztecqt/qbench/common"
+	"github.com/jedib0t/go-pretty/table"
+	"github.com/shopspring/decimal"
+)
+
+// 一组策略参数，参数名->参数值
+type ParamSet map[string]float64
+
+// 读取参数，不存在时返回默认值
+func (p ParamSet) Get(name string, def float64) float64 {
+	if v, ok := p[name]; ok {
+		return v
+	} else {
+		return def
+	}
+}
+
+// 按参数名排序的参数名列表
+func (p ParamSet) names() []string {
+	names := make([]string, 0, len(p))
+	for k := range p {
+		names = append(names, k)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func (p ParamSet) String() string {
+	sb := strings.Builder{}
+	for i, name := range p.names() {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(fmt.Sprintf("%s=%v", name, p[name]))
+	}
+	return sb.String()
+}
+
+// 参数网格，参数名->候选值
+type ParamGrid map[string][]float64
+
+// 展开成所有参数组合（笛卡尔积），顺序是确定的
+func (g ParamGrid) Expand() []ParamSet {
+	names := make([]string, 0, len(g))
+	for k := range g {
+		names = append(names, k)
+	}
+	slices.Sort(names)
+
+	sets := []ParamSet{{}}
+	for _, name := range names {
+		expanded := make([]ParamSet, 0, len(sets)*len(g[name]))
+		for _, ps := range sets {
+			for _, v := range g[name] {
+				nps := ParamSet{}
+				for k, vv := range ps {
+					nps[k] = vv
+				}
+				nps[name] = v
+				expanded = append(expanded, nps)
+			}
+		}
+		sets = expanded
+	}
+
+	return sets
+}
+
+// 参数的取值范围，用于随机搜索
+// Step>0时，只取Min+k*Step形式的值
+type ParamRange struct {
+	Min  float64
+	Max  float64
+	Step float64
+}
+
+// 参数空间，参数名->取值范围
+type ParamSpace map[string]ParamRange
+
+// 随机采样n组参数，相同的种子产生相同的结果
+func (sp ParamSpace) Sample(n int, seed int64) []ParamSet {
+	names := make([]string, 0, len(sp))
+	for k := range sp {
+		names = append(names, k)
+	}
+	slices.Sort(names)
+
+	r := rand.New(rand.NewSource(seed))
+	sets := make([]ParamSet, 0, n)
+	for i := 0; i < n; i++ {
+		ps := ParamSet{}
+		for _, name := range names {
+			pr := sp[name]
+			if pr.Step > 0 {
+				steps := int(math.Floor((pr.Max-pr.Min)/pr.Step + 1e-9))
+				ps[name] = pr.Min + float64(r.Intn(steps+1))*pr.Step
+			} else {
+				ps[name] = pr.Min + r.Float64()*(pr.Max-pr.Min)
+			}
+		}
+		sets = append(sets, ps)
+	}
+
+	return sets
+}
+
+// 参数扫描配置
+type SweepConfig struct {
+	Executor    ExecutorConfig             // 各executor的配置（扫描时总是关闭可视化和单次回测进度）
+	InitBalance map[string]decimal.Decimal // 初始资产
+	Parallel    int                        // 并行数量，0表示cpu核数
+	RankBy      string                     // 排序指标，见Metric_XXX，默认为夏普比率
+}
+
+// 一组参数的回测结果
+type SweepEntry struct {
+	Params ParamSet
+	Result *BacktestResult
+}
+
+// 参数扫描结果，按指标从优到劣排列
+type SweepResult struct {
+	RankBy  string
+	Entries []SweepEntry
+}
+
+// 最优的一组参数
+func (r *SweepResult) Best() (SweepEntry, bool) {
+	if len(r.Entries) > 0 {
+		return r.Entries[0], true
+	} else {
+		return SweepEntry{}, false
+	}
+}
+
+// 生成排名表格，n<=0表示全部
+func (r *SweepResult) ToTable(n int) table.Writer {
+	t := table.NewWriter()
+	t.SetStyle(table.StyleLight)
+
+	names := []string{}
+	if len(r.Entries) > 0 {
+		names = r.Entries[0].Params.names()
+	}
+
+	header := table.Row{"rank"}
+	for _, name := range names {
+		header = append(header, name)
+	}
+	header = append(header, "return", "annual", "volatility", "sharpe", "sortino", "calmar", "max_dd", "win_rate", "trades")
+	t.AppendHeader(header)
+
+	for i, se := range r.Entries {
+		if n > 0 && i >= n {
+			break
+		}
+
+		row := table.Row{i + 1}
+		for _, name := range names {
+			row = append(row, se.Params[name])
+		}
+
+		trades := 0
+		for _, st := range se.Result.Instruments {
+			trades += st.Trades
+		}
+
+		rs := se.Result
+		row = append(row,
+			fmt.Sprintf("%.2f%%", rs.TotalReturn*100),
+			fmt.Sprintf("%.2f%%", rs.AnnualReturn*100),
+			fmt.Sprintf("%.2f%%", rs.AnnualVolatility*100),
+			fmt.Sprintf("%.2f", rs.Sharpe),
+			fmt.Sprintf("%.2f", rs.Sortino),
+			fmt.Sprintf("%.2f", rs.Calmar),
+			fmt.Sprintf("%.2f%%", rs.MaxDrawdown*100),
+			fmt.Sprintf("%.2f%%", rs.WinRate*100),
+			trades)
+		t.AppendRow(row)
+	}
+
+	return t
+}
+
+// 按指标排序，失败的回测排除在外
+func rankSweepEntries(entries []SweepEntry, rankBy string) {
+	higherIsBetter := MetricHigherIsBetter(rankBy)
+	slices.SortStableFunc(entries, func(a, b SweepEntry) int {
+		va, _ := a.Result.Metric(rankBy)
+		vb, _ := b.Result.Metric(rankBy)
+		if va == vb {
+			return 0
+		} else if va > vb == higherIsBetter {
+			return -1
+		} else {
+			return 1
+		}
+	})
+}
+
+// 参数扫描
+// 每组参数由factory创建一个独立的策略实例，在独立的executor上回放同一份预加载行情，并行执行
+// 策略实例的创建在调用者的goroutine中进行，factory不需要是线程安全的
+func Sweep[S Strategy](rd *ReplayData, paramSets []ParamSet, factory func(p ParamSet) S, cfg SweepConfig) (*SweepResult, bool) {
+	if len(cfg.RankBy) == 0 {
+		cfg.RankBy = Metric_Sharpe
+	}
+
+	if _, ok := (&BacktestResult{}).Metric(cfg.RankBy); !ok {
+		common.LogError(logPrefix, "unknown metric %s", cfg.RankBy)
+		return nil, false
+	}
+
+	parallel := cfg.Parallel
+	if parallel <= 0 {
+		parallel = runtime.NumCPU()
+	}
+
+	ecfg := cfg.Executor
+	ecfg.ShowCharts = false
+	ecfg.HideProgress = true
+
+	// 准备executor和策略实例
+	type job struct {
+		e *Executor
+		s S
+	}
+	jobs := make([]job, len(paramSets))
+	for i, ps := range paramSets {
+		e := NewExecutor(rd.localDataPath, ecfg)
+		for ccy, amount := range cfg.InitBalance {
+			e.SetBalance(ccy, amount)
+		}
+		jobs[i] = job{e: e, s: factory(ps)}
+	}
+
+	// 并行回测
+	results := make([]*BacktestResult, len(paramSets))
+	chJob := make(chan int)
+	chDone := make(chan int)
+	wg := sync.WaitGroup{}
+	for w := 0; w < parallel; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range chJob {
+				if r, ok := jobs[i].e.RunReplay(jobs[i].s, rd); ok {
+					results[i] = r
+				}
+				chDone <- i
+			}
+		}()
+	}
+
+	go func() {
+		for i := range jobs {
+			chJob <- i
+		}
+		close(chJob)
+		wg.Wait()
+		close(chDone)
+	}()
+
+	tracker := terminal.GenTrackerWithHardwareInfo("参数扫描", float64(len(jobs)), 30, true, false, true, true, false)
+	for range chDone {
+		tracker.Increment(1)
+	}
+	tracker.MarkAsDone()
+	time.Sleep(time.Millisecond * 100)
+
+	// 排序
+	sr := &SweepResult{RankBy: cfg.RankBy}
+	for i, r := range results {
+		if r == nil {
+			common.LogError(logPrefix, "backtest failed with params: %s", paramSets[i].String())
+			continue
+		}
+		sr.Entries = append(sr.Entries, SweepEntry{Params: paramSets[i], Result: r})
+	}
+	rankSweepEntries(sr.Entries, cfg.RankBy)
+
+	return sr, true
+}
@@ -0,0 +1,105 @@
+/*
+- @Author: aztec
+- @Date: 2026-10-17 10:48:12
+- @Description: 可视化数据组
+- @
+- @Copyright (c) 2024 by aztec, All Rights Reserved.
+*/
+package backtest
+
+import (
+	"time"
+
+	"github.com/aztecqt/dagger/util"
+	"github.com/aztecqt/dagger/util/datavisual"
+	"github.com/aztecqt/qbench/backtest/htmlreport"
+)
+
+// 默认数据组的名称，也是它的保存目录名
+const visualGroup_Default = "default"
+
+// 可视化数据组
+// 嵌入datavisual.DataGroup，写入的数据照常保存给CommonDataViewer查看
+// 同时按曲线名保留一份，html报告直接从这里渲染，与CommonDataViewer看到的是同一份数据
+type VisualGroup struct {
+	*datavisual.DataGroup
+	name    string
+	names   []string                       // 曲线名，按首次写入的顺序
+	lines   map[string][]htmlreport.Point  // 曲线名->数据点
+	markers map[string][]htmlreport.Marker // 曲线名->买卖点
+}
+
+func newVisualGroup(name string, intervalMs int64) *VisualGroup {
+	return &VisualGroup{
+		DataGroup: datavisual.NewDataGroup(intervalMs),
+		name:      name,
+		lines:     map[string][]htmlreport.Point{},
+		markers:   map[string][]htmlreport.Marker{}}
+}
+
+// 记录一个数据点。html报告中画成曲线，买卖点请使用RecordMarker
+func (g *VisualGroup) RecordPoint(name string, p datavisual.Point) {
+	g.DataGroup.RecordPoint(name, p)
+	g.addName(name)
+	g.lines[name] = append(g.lines[name], htmlreport.Point{Time: p.Time, Value: p.Value})
+}
+
+// 记录一个买卖点
+// 在CommonDataViewer中等同于带PointTag_Buy/PointTag_Sell的数据点，在html报告中画成买卖标记而不是曲线
+func (g *VisualGroup) RecordMarker(name string, t time.Time, value float64, isSell bool) {
+	g.DataGroup.RecordPoint(
+		name,
+		datavisual.Point{
+			Time:  t,
+			Value: value,
+			Tag:   util.ValueIf(isSell, datavisual.PointTag_Sell, datavisual.PointTag_Buy)})
+	g.addName(name)
+	g.markers[name] = append(g.markers[name], htmlreport.Marker{Time: t, Value: value, IsSell: isSell})
+}
+
+// 创建一个可视化数据组，同名时返回已有的数据组
+func (e *Executor) NewVisualGroup(name string) *VisualGroup {
+	if name == visualGroup_Default {
+		return e.dgDefault
+	}
+
+	for _, g := range e.visualGroups {
+		if g.name == name {
+			return g
+		}
+	}
+
+	g := newVisualGroup(name, e.cfg.ChartsIntervalMs)
+	e.visualGroups = append(e.visualGroups, g)
+	return g
+}
+
+func (g *VisualGroup) addName(name string) {
+	if _, ok := g.lines[name]; ok {
+		return
+	}
+
+	if _, ok := g.markers[name]; ok {
+		return
+	}
+
+	g.names = append(g.names, name)
+	g.lines[name] = nil
+}
+
+// 把数据组画到html报告中，每条曲线一张图表，同名的买卖点画在同一张图表上
+// 非默认数据组的图表标题带上数据组名称
+func (g *VisualGroup) addToReport(rpt *htmlreport.Report, isDefault bool) {
+	for _, name := range g.names {
+		title := name
+		if !isDefault {
+			title = g.name + "/" + name
+		}
+
+		c := rpt.AddChart(title)
+		if len(g.lines[name]) > 0 {
+			c.AddLine(name, g.lines[name])
+		}
+		c.AddMarkers(g.markers[name])
+	}
+}
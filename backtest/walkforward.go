@@ -0,0 +1,204 @@
+/*
+- @Author: aztec
+- @Date: 2026-10-16 18:02:40
+- @Description: 滚动前推分析（walk-forward）。样本内优化参数，样本外验证，拼接样本外净值
+- @
+- @Copyright (c) 2024 by aztec, All Rights Reserved.
+*/
+package backtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/aztecqt/dagger/util"
+	"github.com/aztecqt/qbench/backtest/htmlreport"
+	"github.com/aztecqt/qbench/common"
+)
+
+// 滚动前推配置
+// 窗口按OutOfSample的长度向前滚动：
+//
+//	[IS_0       ][OOS_0]
+//	       [IS_1       ][OOS_1]
+//	              [IS_2       ][OOS_2]
+//
+// Anchored为true时，样本内窗口的起点固定为整段行情的起点（窗口逐渐变长）
+type WalkForwardConfig struct {
+	Sweep       SweepConfig   // 样本内参数扫描的配置（排序指标即为优化目标）
+	InSample    time.Duration // 样本内窗口长度
+	OutOfSample time.Duration // 样本外窗口长度
+	Anchored    bool          // 固定样本内起点
+}
+
+// 一个滚动窗口的结果
+type WalkForwardWindow struct {
+	InSampleStart    time.Time       `json:"in_sample_start"`
+	InSampleEnd      time.Time       `json:"in_sample_end"`
+	OutOfSampleStart time.Time       `json:"out_of_sample_start"`
+	OutOfSampleEnd   time.Time       `json:"out_of_sample_end"`
+	Params           ParamSet        `json:"params"`        // 样本内最优参数
+	InSample         *BacktestResult `json:"in_sample"`     // 最优参数的样本内结果
+	OutOfSample      *BacktestResult `json:"out_of_sample"` // 最优参数的样本外结果
+}
+
+// 滚动前推结果
+type WalkForwardResult struct {
+	RankBy  string              `json:"rank_by"`
+	Windows []WalkForwardWindow `json:"windows"`
+	Navs    []NavPoint          `json:"navs"` // 拼接后的样本外净值
+
+	PerformanceMetrics // 拼接后的样本外净值的绩效
+}
+
+// 划分滚动窗口，返回各窗口的样本内起点、样本外起点、样本外终点
+func splitWalkForwardWindows(t0, t1 time.Time, cfg WalkForwardConfig) [][3]time.Time {
+	windows := [][3]time.Time{}
+	if cfg.InSample <= 0 || cfg.OutOfSample <= 0 {
+		return windows
+	}
+
+	for isStart := t0; ; isStart = isStart.Add(cfg.OutOfSample) {
+		oosStart := isStart.Add(cfg.InSample)
+		if !oosStart.Before(t1) {
+			break
+		}
+
+		oosEnd := oosStart.Add(cfg.OutOfSample)
+		if oosEnd.After(t1) {
+			oosEnd = t1
+		}
+
+		windows = append(windows, [3]time.Time{util.ValueIf(cfg.Anchored, t0, isStart), oosStart, oosEnd})
+	}
+
+	return windows
+}
+
+// 滚动前推分析
+// 每个窗口先在样本内对paramSets做参数扫描，取最优参数，再用它在紧随其后的样本外窗口上回测
+// 样本外回测总是从初始资产开始，拼接净值时按前一个窗口的期末净值缩放
+func WalkForward[S Strategy](rd *ReplayData, paramSets []ParamSet, factory func(p ParamSet) S, cfg WalkForwardConfig) (*WalkForwardResult, bool) {
+	windows := splitWalkForwardWindows(rd.t0, rd.t1, cfg)
+	if len(windows) == 0 {
+		common.LogError(logPrefix, "no walk-forward window in %s ~ %s", rd.t0.Format(time.DateTime), rd.t1.Format(time.DateTime))
+		return nil, false
+	}
+
+	wfr := &WalkForwardResult{RankBy: util.ValueIf(len(cfg.Sweep.RankBy) > 0, cfg.Sweep.RankBy, Metric_Sharpe)}
+	for i, w := range windows {
+		common.LogNormal(logPrefix, "walk-forward window %d/%d, in-sample %s ~ %s, out-of-sample %s ~ %s",
+			i+1, len(windows),
+			w[0].Format(time.DateTime), w[1].Format(time.DateTime),
+			w[1].Format(time.DateTime), w[2].Format(time.DateTime))
+
+		// 样本内优化
+		sr, ok := Sweep(rd.Slice(w[0], w[1]), paramSets, factory, cfg.Sweep)
+		if !ok {
+			return nil, false
+		}
+
+		best, ok := sr.Best()
+		if !ok {
+			common.LogError(logPrefix, "no valid in-sample result in window %d", i+1)
+			return nil, false
+		}
+
+		// 样本外验证
+		ecfg := cfg.Sweep.Executor
+		ecfg.ShowCharts = false
+		ecfg.HideProgress = true
+		e := NewExecutor(rd.localDataPath, ecfg)
+		for ccy, amount := range cfg.Sweep.InitBalance {
+			e.SetBalance(ccy, amount)
+		}
+
+		oos, ok := e.RunReplay(factory(best.Params), rd.Slice(w[1], w[2]))
+		if !ok {
+			common.LogError(logPrefix, "out-of-sample backtest failed in window %d", i+1)
+			return nil, false
+		}
+
+		wfr.Windows = append(wfr.Windows, WalkForwardWindow{
+			InSampleStart:    w[0],
+			InSampleEnd:      w[1],
+			OutOfSampleStart: w[1],
+			OutOfSampleEnd:   w[2],
+			Params:           best.Params,
+			InSample:         best.Result,
+			OutOfSample:      oos})
+	}
+
+	wfr.Navs = stitchNavs(wfr.Windows)
+	wfr.PerformanceMetrics = CalcPerformance(wfr.Navs)
+	return wfr, true
+}
+
+// 拼接各窗口的样本外净值
+func stitchNavs(windows []WalkForwardWindow) []NavPoint {
+	navs := []NavPoint{}
+	scale := 1.0
+	for _, w := range windows {
+		wnavs := w.OutOfSample.Navs
+		if len(wnavs) == 0 || wnavs[0].Nav <= 0 {
+			continue
+		}
+
+		base := wnavs[0].Nav
+		for _, p := range wnavs {
+			navs = append(navs, NavPoint{Time: p.Time, Nav: scale * p.Nav / base})
+		}
+		scale = navs[len(navs)-1].Nav
+	}
+
+	return navs
+}
+
+// 保存为json文件
+func (r *WalkForwardResult) SaveJSON(path string) bool {
+	if b, err := json.MarshalIndent(r, "", "  "); err == nil {
+		return common.SaveFile(logPrefix, path, b)
+	} else {
+		common.LogError(logPrefix, "marshal walk-forward result failed: %s", err.Error())
+		return false
+	}
+}
+
+// 保存为html报告
+func (r *WalkForwardResult) SaveHtml(path string) bool {
+	rpt := htmlreport.NewReport("Walk-Forward 报告")
+	rpt.AddInfo("优化指标：%s", r.RankBy)
+	rpt.AddInfo("样本外年化收益：%.2f%%  年化波动：%.2f%%", r.AnnualReturn*100, r.AnnualVolatility*100)
+	rpt.AddInfo("样本外夏普比率：%.2f  索提诺比率：%.2f  卡玛比率：%.2f", r.Sharpe, r.Sortino, r.Calmar)
+	rpt.AddInfo("样本外最大回撤：%.2f%%", r.MaxDrawdown*100)
+	rpt.AddInfo("")
+	for i, w := range r.Windows {
+		isMetric, _ := w.InSample.Metric(r.RankBy)
+		oosMetric, _ := w.OutOfSample.Metric(r.RankBy)
+		rpt.AddInfo("窗口%d  样本外 %s ~ %s  %s: 样本内%.4f 样本外%.4f  参数: %s",
+			i+1,
+			w.OutOfSampleStart.Format(time.DateTime),
+			w.OutOfSampleEnd.Format(time.DateTime),
+			r.RankBy, isMetric, oosMetric,
+			w.Params.String())
+	}
+
+	points := make([]htmlreport.Point, 0, len(r.Navs))
+	for _, p := range r.Navs {
+		points = append(points, htmlreport.Point{Time: p.Time, Value: p.Nav})
+	}
+	rpt.AddChart("样本外净值（拼接）").AddLine("nav", points)
+
+	// 各窗口单独的样本外净值
+	c := rpt.AddChart("各窗口样本外净值")
+	for i, w := range r.Windows {
+		wpoints := make([]htmlreport.Point, 0, len(w.OutOfSample.Navs))
+		for _, p := range w.OutOfSample.Navs {
+			wpoints = append(wpoints, htmlreport.Point{Time: p.Time, Value: p.Nav})
+		}
+		c.AddLine(fmt.Sprintf("窗口%d", i+1), wpoints)
+	}
+
+	return rpt.SaveToFile(path)
+}
@@ -0,0 +1,19 @@
+/*
+- @Author: aztec
+- @Date: 2026-10-17 01:07:44
+- @Description: 命令行回测工具。读取任务文件，无界面运行回测
+- @
+- @Copyright (c) 2024 by aztec, All Rights Reserved.
+*/
+package main
+
+import (
+	_ "github.com/aztecqt/qbench/backtest/example"
+	"github.com/aztecqt/qbench/backtest/job"
+)
+
+// 用法：qbench -job job.yaml [-out 输出目录]
+// 只链接了示例策略（backtest/example）。运行自己的策略时，在自己的main中匿名导入策略包，然后调用job.Main()
+func main() {
+	job.Main()
+}
@@ -49,6 +49,9 @@ type ContractPosition struct {
 	TotalVolume           decimal.Decimal // 总成交量
 	ClearCount            int             // 完全平仓次数
 	ProfitRecords         []ProfitRecord  // 每次平仓后，记录仓前总利润
+	TotalFunding          decimal.Decimal // 累计资金费，正数表示收入，负数表示支出
+	Leverage              decimal.Decimal // 杠杆倍数（逐仓），0表示不计算保证金和强平
+	MaintenanceMarginRate decimal.Decimal // 维持保证金率
 	maxPositionAbs        decimal.Decimal // 最大仓位数量
 }
 
@@ -146,7 +149,9 @@ func (c *ContractPosition) PositionDir() common.OrderDir {
 	}
 }
 
-// 总收益
+// 总收益（已实现+未实现-手续费）
+// 不含资金费，资金费单独累计在TotalFunding中。executor在结算时直接把资金费计入余额，
+// 把TotalProfit与余额相加不会重复计算资金费
 func (c *ContractPosition) TotalProfit() decimal.Decimal {
 	return c.RealizedProfit.Add(c.UnRealizedProfit).Sub(c.TotalFee)
 }
@@ -182,12 +187,12 @@ func (c *ContractPosition) Deal(price decimal.Decimal, amount decimal.Decimal, t
 	} else {
 		// 平仓情况，更新总仓位，已实现利润，平仓均价
 		if amountAbs.GreaterThan(positionAbs) {
-			// 分两次计算
+			// 分两次计算，手续费和利润为两次之和
 			amount0 := c.Position.Neg()
 			amount1 := amount.Add(c.Position)
-			c.Deal(price, amount0, taker, t, fnPosClear)
-			c.Deal(price, amount1, taker, t, fnPosClear)
-			return
+			fee0, profit0 := c.Deal(price, amount0, taker, t, fnPosClear)
+			fee1, profit1 := c.Deal(price, amount1, taker, t, fnPosClear)
+			return fee0.Add(fee1), profit0.Add(profit1)
 		}
 
 		// 此时amount的绝对值必然小于等于Position
@@ -265,6 +270,136 @@ func (c *ContractPosition) Update(currentPrice decimal.Decimal) {
 	c.UnRealizedProfit = pft
 }
 
+// 按标记价格结算一次资金费，累计到TotalFunding（不计入TotalProfit）
+// 返回本次资金费，正数表示收入，负数表示支出（以保证金币种计）
+// 费率为正时多头支付给空头：
+//
+//	U本位合约：资金费 = -仓位（币）* 标记价格 * 费率
+//	币本位合约：资金费 = -仓位（U）/ 标记价格 * 费率
+func (c *ContractPosition) Funding(rate, markPrice decimal.Decimal) decimal.Decimal {
+	if c.Position.IsZero() || !markPrice.IsPositive() {
+		return decimal.Zero
+	}
+
+	payment := decimal.Zero
+	if c.isUsdt {
+		payment = c.Position.Mul(markPrice).Mul(rate).Neg()
+	} else {
+		payment = c.Position.Div(markPrice).Mul(rate).Neg()
+	}
+
+	c.TotalFunding = c.TotalFunding.Add(payment)
+
+	if c.enableLog {
+		fmt.Printf("funding: rate={%v}, price={%v}, position={%v}, payment={%v}\n", rate, markPrice, c.Position, payment)
+	}
+
+	return payment
+}
+
+// 设置杠杆倍数和维持保证金率（逐仓模式）
+// leverage为0表示不计算保证金和强平
+func (c *ContractPosition) SetMargin(leverage, maintenanceMarginRate decimal.Decimal) {
+	c.Leverage = leverage
+	c.MaintenanceMarginRate = maintenanceMarginRate
+}
+
+// 是否启用了保证金计算
+func (c *ContractPosition) MarginEnabled() bool {
+	return c.Leverage.IsPositive()
+}
+
+// 名义价值（以保证金币种计）
+// U本位：仓位（币）* 价格
+// 币本位：仓位（U）/ 价格
+func (c *ContractPosition) notional(amount, price decimal.Decimal) decimal.Decimal {
+	if !price.IsPositive() {
+		return decimal.Zero
+	}
+
+	if c.isUsdt {
+		return amount.Abs().Mul(price)
+	} else {
+		return amount.Abs().Div(price)
+	}
+}
+
+// 开仓所需的保证金 = 按开仓均价计算的名义价值 / 杠杆
+func (c *ContractPosition) InitialMargin() decimal.Decimal {
+	if !c.MarginEnabled() {
+		return decimal.Zero
+	}
+
+	return c.notional(c.Position, c.PositionAvgPriceOpen).Div(c.Leverage)
+}
+
+// 以指定价格开仓amount所需的保证金
+func (c *ContractPosition) InitialMarginOf(price, amount decimal.Decimal) decimal.Decimal {
+	if !c.MarginEnabled() {
+		return decimal.Zero
+	}
+
+	return c.notional(amount, price).Div(c.Leverage)
+}
+
+// 维持保证金 = 按标记价格计算的名义价值 * 维持保证金率
+func (c *ContractPosition) MaintenanceMargin(markPrice decimal.Decimal) decimal.Decimal {
+	return c.notional(c.Position, markPrice).Mul(c.MaintenanceMarginRate)
+}
+
+// 强平价格
+// 逐仓模式下，当 初始保证金 + 未实现盈亏 = 维持保证金 时触发强平。记pos为带符号的仓位，im为初始保证金：
+//
+//	U本位：im + pos*(P-开仓均价) = |pos|*P*mmr，解得 P = (pos*开仓均价 - im) / (pos - |pos|*mmr)
+//	币本位：im + pos/开仓均价 - pos/P = |pos|*mmr/P，解得 P = (pos + |pos|*mmr) / (im + pos/开仓均价)
+//
+// 无仓位、未启用保证金、或永远不会被强平（如1倍杠杆的币本位空单）时，ok返回false
+func (c *ContractPosition) LiquidationPrice() (price decimal.Decimal, ok bool) {
+	if !c.MarginEnabled() || c.Position.IsZero() || !c.PositionAvgPriceOpen.IsPositive() {
+		return decimal.Zero, false
+	}
+
+	im := c.InitialMargin()
+	pos := c.Position
+	posAbs := c.Position.Abs()
+	avg := c.PositionAvgPriceOpen
+	mmr := c.MaintenanceMarginRate
+
+	numerator := decimal.Zero
+	denominator := decimal.Zero
+	if c.isUsdt {
+		numerator = pos.Mul(avg).Sub(im)
+		denominator = pos.Sub(posAbs.Mul(mmr))
+	} else {
+		numerator = pos.Add(posAbs.Mul(mmr))
+		denominator = im.Add(pos.Div(avg))
+	}
+
+	if denominator.IsZero() {
+		return decimal.Zero, false
+	}
+
+	price = numerator.Div(denominator)
+	if !price.IsPositive() {
+		return decimal.Zero, false
+	}
+
+	return price, true
+}
+
+// 标记价格是否已经触及强平价格
+func (c *ContractPosition) ShouldLiquidate(markPrice decimal.Decimal) bool {
+	if liqPx, ok := c.LiquidationPrice(); ok {
+		if c.Position.IsPositive() {
+			return markPrice.LessThanOrEqual(liqPx)
+		} else {
+			return markPrice.GreaterThanOrEqual(liqPx)
+		}
+	}
+
+	return false
+}
+
 // 最近一次完整平仓的收益
 func (c *ContractPosition) LastPositionProfit() decimal.Decimal {
 	if len(c.ProfitRecords) == 0 {
@@ -0,0 +1,97 @@
+package common_test
+
+import (
+	"testing"
+
+	"github.com/aztecqt/qbench/common"
+	"github.com/aztecqt/qbench/internal/testutil"
+	"github.com/shopspring/decimal"
+)
+
+func TestContractPositionLiquidationPrice(t *testing.T) {
+	cases := []struct {
+		name     string
+		isUsdt   bool
+		position float64
+		avgPrice float64
+		leverage float64
+		mmr      float64
+		expectOk bool
+		expectPx float64 // 保留6位小数比较
+	}{
+		{"usdt long", true, 1, 100, 10, 0.005, true, 90.452261},
+		{"usdt short", true, -1, 100, 10, 0.005, true, 109.452736},
+		{"usdt long 1x without mmr", true, 1, 100, 1, 0, false, 0},
+		{"coin long", false, 100, 100, 10, 0.005, true, 91.363636},
+		{"coin short", false, -100, 100, 10, 0.005, true, 110.555556},
+		{"coin short 1x", false, -100, 100, 1, 0.005, false, 0},
+		{"margin disabled", true, 1, 100, 0, 0.005, false, 0},
+		{"no position", true, 0, 100, 10, 0.005, false, 0},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			p := common.NewContractPosition(decimal.Zero, decimal.Zero, c.isUsdt, false, "")
+			p.SetMargin(testutil.Dec(c.leverage), testutil.Dec(c.mmr))
+			p.Position = testutil.Dec(c.position)
+			p.PositionAvgPriceOpen = testutil.Dec(c.avgPrice)
+
+			px, ok := p.LiquidationPrice()
+			if ok != c.expectOk {
+				t.Fatalf("expect ok=%v, got %v (price=%v)", c.expectOk, ok, px)
+			}
+
+			if ok && !px.Round(6).Equal(testutil.Dec(c.expectPx)) {
+				t.Errorf("expect price %v, got %v", c.expectPx, px)
+			}
+
+			// 强平价格上应当恰好触发强平
+			if ok && !p.ShouldLiquidate(px) {
+				t.Errorf("should liquidate at %v", px)
+			}
+		})
+	}
+}
+
+func TestContractPositionFunding(t *testing.T) {
+	cases := []struct {
+		name      string
+		isUsdt    bool
+		position  float64
+		rate      float64
+		markPrice float64
+		expect    float64
+	}{
+		{"usdt long pays", true, 2, 0.0001, 100, -0.02},
+		{"usdt short receives", true, -2, 0.0001, 100, 0.02},
+		{"usdt long negative rate", true, 2, -0.0001, 100, 0.02},
+		{"coin long pays", false, 100, 0.001, 50, -0.002},
+		{"coin short receives", false, -100, 0.001, 50, 0.002},
+		{"no position", true, 0, 0.0001, 100, 0},
+		{"invalid mark price", true, 2, 0.0001, 0, 0},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			p := common.NewContractPosition(decimal.Zero, decimal.Zero, c.isUsdt, false, "")
+			p.Position = testutil.Dec(c.position)
+
+			// 连续结算两次，累计资金费应为两次之和
+			for i := 1; i <= 2; i++ {
+				got := p.Funding(testutil.Dec(c.rate), testutil.Dec(c.markPrice))
+				if !got.Equal(testutil.Dec(c.expect)) {
+					t.Errorf("expect payment %v, got %v", c.expect, got)
+				}
+
+				if !p.TotalFunding.Equal(testutil.Dec(c.expect).Mul(decimal.NewFromInt(int64(i)))) {
+					t.Errorf("unexpected total funding %v after %d payments", p.TotalFunding, i)
+				}
+
+				// 资金费不计入交易收益
+				if !p.TotalProfit().IsZero() {
+					t.Errorf("funding should not be counted in total profit, got %v", p.TotalProfit())
+				}
+			}
+		})
+	}
+}
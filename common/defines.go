@@ -544,5 +544,33 @@ func (t *Trade) Deserialize(r io.Reader) bool {
 	return true
 }
 
+// 资金费率（永续合约）
+type FundingRate struct {
+	Time time.Time       // 结算时间
+	Rate decimal.Decimal // 费率，正数表示多头支付给空头
+}
+
+func (f FundingRate) Serialize(w io.Writer) bool {
+	binary.Write(w, binary.LittleEndian, f.Time.UnixMilli())
+	binary.Write(w, binary.LittleEndian, f.Rate.InexactFloat64())
+	return true
+}
+
+func (f *FundingRate) Deserialize(r io.Reader) bool {
+	ms := int64(0)
+	if binary.Read(r, binary.LittleEndian, &ms) != nil {
+		return false
+	}
+	f.Time = time.UnixMilli(ms)
+
+	val := 0.0
+	if binary.Read(r, binary.LittleEndian, &val) != nil {
+		return false
+	}
+	f.Rate = decimal.NewFromFloat(val)
+
+	return true
+}
+
 //
 // #endregion
@@ -0,0 +1,113 @@
+/*
+- @Author: aztec
+- @Date: 2026-10-16 13:10:44
+- @Description: 双向持仓
+- @
+- @Copyright (c) 2024 by aztec, All Rights Reserved.
+*/
+package common
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// 持仓方向
+type PosSide string
+
+const (
+	PosSide_Net   PosSide = ""      // 单向持仓（净仓位）
+	PosSide_Long  PosSide = "long"  // 双向持仓的多头
+	PosSide_Short PosSide = "short" // 双向持仓的空头
+)
+
+// 模拟一个双向持仓（对冲模式）
+// 同一个品种可以同时持有多头和空头，两侧独立计算开仓均价、已实现盈亏、平仓记录等
+// 多头仓位始终>=0，空头仓位始终<=0
+type DualSidePosition struct {
+	MarginCcy string
+	Long      *ContractPosition
+	Short     *ContractPosition
+}
+
+func NewDualSidePosition(feeRateMaker, feeRateTaker decimal.Decimal, isUsdt, enableLog bool, marginCcy string) *DualSidePosition {
+	d := new(DualSidePosition)
+	d.MarginCcy = marginCcy
+	d.Long = NewContractPosition(feeRateMaker, feeRateTaker, isUsdt, enableLog, marginCcy)
+	d.Short = NewContractPosition(feeRateMaker, feeRateTaker, isUsdt, enableLog, marginCcy)
+	return d
+}
+
+// 取某一侧的仓位
+func (d *DualSidePosition) Side(side PosSide) (*ContractPosition, bool) {
+	switch side {
+	case PosSide_Long:
+		return d.Long, true
+	case PosSide_Short:
+		return d.Short, true
+	default:
+		return nil, false
+	}
+}
+
+// 在某一侧记录一次交易
+// amount正数为买入，负数为卖出。多头买入开仓、卖出平仓；空头卖出开仓、买入平仓
+// 平仓数量超过该侧仓位时只平掉现有仓位，不会反向开仓
+// 返回手续费、已实现利润、实际成交数量（带符号）
+func (d *DualSidePosition) Deal(side PosSide, price, amount decimal.Decimal, taker bool, t time.Time, fnPosClear func()) (fee, profit, dealt decimal.Decimal) {
+	pos, ok := d.Side(side)
+	if !ok {
+		return decimal.Zero, decimal.Zero, decimal.Zero
+	}
+
+	if side == PosSide_Long && amount.IsNegative() && amount.Abs().GreaterThan(pos.Position) {
+		amount = pos.Position.Neg()
+	} else if side == PosSide_Short && amount.IsPositive() && amount.GreaterThan(pos.Position.Abs()) {
+		amount = pos.Position.Abs()
+	}
+
+	if amount.IsZero() {
+		return decimal.Zero, decimal.Zero, decimal.Zero
+	}
+
+	fee, profit = pos.Deal(price, amount, taker, t, fnPosClear)
+	return fee, profit, amount
+}
+
+// 净仓位（多头+空头）
+func (d *DualSidePosition) NetPosition() decimal.Decimal {
+	return d.Long.Position.Add(d.Short.Position)
+}
+
+// 根据当前价格，重新计算两侧的未实现盈亏
+func (d *DualSidePosition) Update(currentPrice decimal.Decimal) {
+	d.Long.Update(currentPrice)
+	d.Short.Update(currentPrice)
+}
+
+// 两侧的未实现盈亏之和
+func (d *DualSidePosition) UnRealizedProfit() decimal.Decimal {
+	return d.Long.UnRealizedProfit.Add(d.Short.UnRealizedProfit)
+}
+
+// 两侧的总收益之和（不含资金费）
+func (d *DualSidePosition) TotalProfit() decimal.Decimal {
+	return d.Long.TotalProfit().Add(d.Short.TotalProfit())
+}
+
+// 两侧的累计资金费之和
+func (d *DualSidePosition) TotalFunding() decimal.Decimal {
+	return d.Long.TotalFunding.Add(d.Short.TotalFunding)
+}
+
+// 两侧分别结算资金费，返回合计
+func (d *DualSidePosition) Funding(rate, markPrice decimal.Decimal) decimal.Decimal {
+	return d.Long.Funding(rate, markPrice).Add(d.Short.Funding(rate, markPrice))
+}
+
+// 两侧设置相同的杠杆倍数和维持保证金率
+func (d *DualSidePosition) SetMargin(leverage, maintenanceMarginRate decimal.Decimal) {
+	d.Long.SetMargin(leverage, maintenanceMarginRate)
+	d.Short.SetMargin(leverage, maintenanceMarginRate)
+}
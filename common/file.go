@@ -0,0 +1,43 @@
+/*
+- @Author: aztec
+- @Date: 2026-10-17 10:21:37
+- @Description: 结果文件的保存
+- @
+- @Copyright (c) 2024 by aztec, All Rights Reserved.
+*/
+package common
+
+import (
+	"bytes"
+	"encoding/csv"
+	"os"
+
+	"github.com/aztecqt/dagger/util"
+)
+
+// 输出文件的权限，所有者可读写，其他人只读
+const FileMode os.FileMode = 0644
+
+// 保存数据到文件，自动创建目录
+// 失败时以prefix为前缀记录错误日志
+func SaveFile(prefix, path string, b []byte) bool {
+	util.MakeSureDirForFile(path)
+	if err := os.WriteFile(path, b, FileMode); err == nil {
+		return true
+	} else {
+		LogError(prefix, "save %s failed: %s", path, err.Error())
+		return false
+	}
+}
+
+// 保存为csv文件，rows的第一行一般为表头
+func SaveCSV(prefix, path string, rows [][]string) bool {
+	buf := bytes.Buffer{}
+	w := csv.NewWriter(&buf)
+	if err := w.WriteAll(rows); err != nil {
+		LogError(prefix, "write %s failed: %s", path, err.Error())
+		return false
+	}
+
+	return SaveFile(prefix, path, buf.Bytes())
+}
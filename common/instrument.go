@@ -0,0 +1,56 @@
+/*
+- @Author: aztec
+- @Date: 2026-10-16 19:48:11
+- @Description: 交易品种规则
+- @
+- @Copyright (c) 2024 by aztec, All Rights Reserved.
+*/
+package common
+
+import (
+	"github.com/shopspring/decimal"
+)
+
+// 交易品种规则
+// 各字段为0表示不限制
+type InstrumentRule struct {
+	InstId      string          `json:"inst_id"`      // 通用instId
+	TickSize    decimal.Decimal `json:"tick_size"`    // 价格精度
+	LotSize     decimal.Decimal `json:"lot_size"`     // 数量精度
+	MinNotional decimal.Decimal `json:"min_notional"` // 最小下单金额（计价币种），币本位合约的数量本身就是金额
+	MaxAmount   decimal.Decimal `json:"max_amount"`   // 单笔最大下单数量
+}
+
+// 价格对齐到TickSize
+// 买单向下取整、卖单向上取整，即总是取对自己更保守的价格
+func (r InstrumentRule) RoundPrice(price decimal.Decimal, isSell bool) decimal.Decimal {
+	if !r.TickSize.IsPositive() {
+		return price
+	}
+
+	n := price.Div(r.TickSize)
+	if isSell {
+		n = n.Ceil()
+	} else {
+		n = n.Floor()
+	}
+	return n.Mul(r.TickSize)
+}
+
+// 数量对齐到LotSize（向下取整）
+func (r InstrumentRule) RoundAmount(amount decimal.Decimal) decimal.Decimal {
+	if !r.LotSize.IsPositive() {
+		return amount
+	}
+
+	return amount.Div(r.LotSize).Floor().Mul(r.LotSize)
+}
+
+// 下单金额
+func (r InstrumentRule) Notional(price, amount decimal.Decimal) decimal.Decimal {
+	if GetInstType(r.InstId) == InstType_CmSwap {
+		return amount
+	} else {
+		return price.Mul(amount)
+	}
+}
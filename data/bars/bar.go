@@ -0,0 +1,152 @@
+/*
+- @Author: aztec
+- @Date: 2026-10-16 22:14:08
+- @Description: 用逐笔成交合成bar，支持时间bar、tick bar、成交量bar、成交额bar
+- @
+- @Copyright (c) 2024 by aztec, All Rights Reserved.
+*/
+package bars
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aztecqt/qbench/common"
+	"github.com/shopspring/decimal"
+)
+
+const logPrefix = "bars"
+
+// bar的类型
+type BarType string
+
+const (
+	BarType_Time   BarType = "time"   // 按固定时间周期切分
+	BarType_Tick   BarType = "tick"   // 按成交笔数切分
+	BarType_Volume BarType = "volume" // 按成交数量切分
+	BarType_Dollar BarType = "dollar" // 按成交额（价格*数量）切分
+)
+
+// bar的规格
+type Spec struct {
+	Type       BarType `json:"type"`
+	IntervalMs int64   `json:"interval_ms"` // 时间bar的周期，可以是秒级甚至毫秒级
+	Threshold  float64 `json:"threshold"`   // 其他bar的阈值：tick bar为成交笔数，volume bar为成交数量，dollar bar为成交额
+}
+
+func (s Spec) String() string {
+	if s.Type == BarType_Time {
+		return fmt.Sprintf("%s_%s", s.Type, time.Duration(s.IntervalMs)*time.Millisecond)
+	} else {
+		return fmt.Sprintf("%s_%v", s.Type, s.Threshold)
+	}
+}
+
+// 一根bar
+// 时间bar的起止时间为所在周期的起止，没有成交的周期不产生bar
+// 其他bar的起止时间为第一笔、最后一笔成交的时间。一笔成交不会被拆分，因此最后一笔成交可能使累计量超过阈值
+type Bar struct {
+	OpenTime  time.Time
+	CloseTime time.Time
+	Open      decimal.Decimal
+	High      decimal.Decimal
+	Low       decimal.Decimal
+	Close     decimal.Decimal
+	Volume    decimal.Decimal // 成交数量
+	BuyVolume decimal.Decimal // 主动买入的成交数量
+	Turnover  decimal.Decimal // 成交额（价格*数量）
+	Trades    int             // 成交笔数
+}
+
+// 转换为k线单元，时间为开盘时间
+func (b Bar) ToKlineUnit() common.KlineUnit {
+	return common.KlineUnit{
+		Time:       b.OpenTime,
+		OpenPrice:  b.Open,
+		ClosePrice: b.Close,
+		HighPrice:  b.High,
+		LowPrice:   b.Low,
+		Volume:     b.Volume,
+	}
+}
+
+// 累加一笔成交
+func (b *Bar) add(t common.Trade) {
+	if b.Trades == 0 {
+		b.Open = t.Price
+		b.High = t.Price
+		b.Low = t.Price
+	} else {
+		b.High = decimal.Max(b.High, t.Price)
+		b.Low = decimal.Min(b.Low, t.Price)
+	}
+
+	b.Close = t.Price
+	b.Volume = b.Volume.Add(t.Size)
+	if t.Side == 'b' {
+		b.BuyVolume = b.BuyVolume.Add(t.Size)
+	}
+	b.Turnover = b.Turnover.Add(t.Price.Mul(t.Size))
+	b.Trades++
+}
+
+// bar合成器
+// 成交需要按时间顺序输入。离线和回放中都可以使用
+type Builder interface {
+	// 输入一笔成交，如果因此完成了一根bar，返回这根bar
+	Add(t common.Trade) (Bar, bool)
+
+	// 时间推进到t，如果当前bar已经到期（仅时间bar），结束并返回当前bar
+	CloseAt(t time.Time) (Bar, bool)
+
+	// 当前尚未完成的bar
+	Current() (Bar, bool)
+
+	// 强制结束当前尚未完成的bar
+	Flush() (Bar, bool)
+}
+
+// 根据规格创建bar合成器
+func NewBuilder(spec Spec) (Builder, bool) {
+	switch spec.Type {
+	case BarType_Time:
+		if spec.IntervalMs <= 0 {
+			common.LogError(logPrefix, "invalid interval %d for time bar", spec.IntervalMs)
+			return nil, false
+		}
+		return &timeBuilder{interval: time.Duration(spec.IntervalMs) * time.Millisecond}, true
+	case BarType_Tick:
+		return newThresholdBuilder(spec, func(t common.Trade) decimal.Decimal { return decimal.NewFromInt(1) })
+	case BarType_Volume:
+		return newThresholdBuilder(spec, func(t common.Trade) decimal.Decimal { return t.Size })
+	case BarType_Dollar:
+		return newThresholdBuilder(spec, func(t common.Trade) decimal.Decimal { return t.Price.Mul(t.Size) })
+	default:
+		common.LogError(logPrefix, "unknown bar type: %s", spec.Type)
+		return nil, false
+	}
+}
+
+// 把一段成交离线合成为bar
+// flush为true时，最后一根未完成的bar也包含在结果中
+func Build(trades []common.Trade, spec Spec, flush bool) ([]Bar, bool) {
+	b, ok := NewBuilder(spec)
+	if !ok {
+		return nil, false
+	}
+
+	result := []Bar{}
+	for _, t := range trades {
+		if bar, ok := b.Add(t); ok {
+			result = append(result, bar)
+		}
+	}
+
+	if flush {
+		if bar, ok := b.Flush(); ok {
+			result = append(result, bar)
+		}
+	}
+
+	return result, true
+}
@@ -0,0 +1,100 @@
+package bars
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aztecqt/qbench/common"
+	"github.com/aztecqt/qbench/internal/testutil"
+)
+
+var testT0 = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func testTrade(sec int, price, size float64, side byte) common.Trade {
+	return testutil.Trade(testT0.Add(time.Second*time.Duration(sec)), price, size, side)
+}
+
+// 期望的bar，时间以相对testT0的秒数表示
+type testBar struct {
+	openSec, closeSec           int
+	open, high, low, close      float64
+	volume, buyVolume, turnover float64
+	trades                      int
+}
+
+func (e testBar) equal(b Bar) bool {
+	d := testutil.Dec
+	return b.OpenTime.Equal(testT0.Add(time.Second*time.Duration(e.openSec))) &&
+		b.CloseTime.Equal(testT0.Add(time.Second*time.Duration(e.closeSec))) &&
+		b.Open.Equal(d(e.open)) &&
+		b.High.Equal(d(e.high)) &&
+		b.Low.Equal(d(e.low)) &&
+		b.Close.Equal(d(e.close)) &&
+		b.Volume.Equal(d(e.volume)) &&
+		b.BuyVolume.Equal(d(e.buyVolume)) &&
+		b.Turnover.Equal(d(e.turnover)) &&
+		b.Trades == e.trades
+}
+
+func TestBuild(t *testing.T) {
+	trades := []common.Trade{
+		testTrade(0, 100, 1, 'b'),
+		testTrade(20, 102, 2, 's'),
+		testTrade(59, 99, 1, 'b'),
+		testTrade(60, 101, 3, 'b'),
+		testTrade(130, 103, 1, 's'),
+	}
+
+	cases := []struct {
+		name     string
+		spec     Spec
+		flush    bool
+		expectOk bool
+		expect   []testBar
+	}{
+		{"time", Spec{Type: BarType_Time, IntervalMs: 60000}, false, true, []testBar{
+			{0, 60, 100, 102, 99, 99, 4, 2, 403, 3},
+			{60, 120, 101, 101, 101, 101, 3, 3, 303, 1},
+		}},
+		{"time flush", Spec{Type: BarType_Time, IntervalMs: 60000}, true, true, []testBar{
+			{0, 60, 100, 102, 99, 99, 4, 2, 403, 3},
+			{60, 120, 101, 101, 101, 101, 3, 3, 303, 1},
+			{120, 180, 103, 103, 103, 103, 1, 0, 103, 1},
+		}},
+		{"tick flush", Spec{Type: BarType_Tick, Threshold: 2}, true, true, []testBar{
+			{0, 20, 100, 102, 100, 102, 3, 1, 304, 2},
+			{59, 60, 99, 101, 99, 101, 4, 4, 402, 2},
+			{130, 130, 103, 103, 103, 103, 1, 0, 103, 1},
+		}},
+		{"volume", Spec{Type: BarType_Volume, Threshold: 4}, false, true, []testBar{
+			{0, 59, 100, 102, 99, 99, 4, 2, 403, 3},
+			{60, 130, 101, 103, 101, 103, 4, 3, 406, 2},
+		}},
+		{"dollar", Spec{Type: BarType_Dollar, Threshold: 250}, false, true, []testBar{
+			{0, 20, 100, 102, 100, 102, 3, 1, 304, 2},
+			{59, 60, 99, 101, 99, 101, 4, 4, 402, 2},
+		}},
+		{"invalid interval", Spec{Type: BarType_Time}, false, false, nil},
+		{"invalid threshold", Spec{Type: BarType_Volume}, false, false, nil},
+		{"unknown type", Spec{Type: "renko", Threshold: 1}, false, false, nil},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			bars, ok := Build(trades, c.spec, c.flush)
+			if ok != c.expectOk {
+				t.Fatalf("expect ok=%v, got %v", c.expectOk, ok)
+			}
+
+			if len(bars) != len(c.expect) {
+				t.Fatalf("expect %d bars, got %d: %+v", len(c.expect), len(bars), bars)
+			}
+
+			for i, e := range c.expect {
+				if !e.equal(bars[i]) {
+					t.Errorf("bar %d: expect %+v, got %+v", i, e, bars[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+/*
+- @Author: aztec
+- @Date: 2026-10-16 22:31:45
+- @Description: 各类bar合成器的实现
+- @
+- @Copyright (c) 2024 by aztec, All Rights Reserved.
+*/
+package bars
+
+import (
+	"time"
+
+	"github.com/aztecqt/qbench/common"
+	"github.com/shopspring/decimal"
+)
+
+// 时间bar合成器
+// 周期按绝对时间对齐（如1分钟bar从整分开始）
+type timeBuilder struct {
+	interval time.Duration
+	cur      Bar
+	has      bool
+}
+
+func (b *timeBuilder) Add(t common.Trade) (bar Bar, ok bool) {
+	start := t.Time.Truncate(b.interval)
+
+	// 进入了新的周期，结束当前bar。时间回退的成交仍归入当前bar
+	if b.has && start.After(b.cur.OpenTime) {
+		bar, ok = b.cur, true
+		b.has = false
+	}
+
+	if !b.has {
+		b.cur = Bar{OpenTime: start, CloseTime: start.Add(b.interval)}
+		b.has = true
+	}
+
+	b.cur.add(t)
+	return
+}
+
+func (b *timeBuilder) CloseAt(t time.Time) (Bar, bool) {
+	if b.has && !t.Before(b.cur.CloseTime) {
+		b.has = false
+		return b.cur, true
+	} else {
+		return Bar{}, false
+	}
+}
+
+func (b *timeBuilder) Current() (Bar, bool) {
+	return b.cur, b.has
+}
+
+func (b *timeBuilder) Flush() (Bar, bool) {
+	if b.has {
+		b.has = false
+		return b.cur, true
+	} else {
+		return Bar{}, false
+	}
+}
+
+// 阈值bar合成器，累计量达到阈值时结束当前bar
+// tick bar、成交量bar、成交额bar只是累计量的计算方式不同
+type thresholdBuilder struct {
+	threshold decimal.Decimal
+	measure   func(t common.Trade) decimal.Decimal
+	acc       decimal.Decimal
+	cur       Bar
+	has       bool
+}
+
+func newThresholdBuilder(spec Spec, measure func(t common.Trade) decimal.Decimal) (Builder, bool) {
+	if spec.Threshold <= 0 {
+		common.LogError(logPrefix, "invalid threshold %v for %s bar", spec.Threshold, spec.Type)
+		return nil, false
+	}
+
+	return &thresholdBuilder{threshold: decimal.NewFromFloat(spec.Threshold), measure: measure}, true
+}
+
+func (b *thresholdBuilder) Add(t common.Trade) (Bar, bool) {
+	if !b.has {
+		b.cur = Bar{OpenTime: t.Time}
+		b.acc = decimal.Zero
+		b.has = true
+	}
+
+	b.cur.add(t)
+	b.cur.CloseTime = t.Time
+	b.acc = b.acc.Add(b.measure(t))
+	if b.acc.GreaterThanOrEqual(b.threshold) {
+		b.has = false
+		return b.cur, true
+	} else {
+		return Bar{}, false
+	}
+}
+
+func (b *thresholdBuilder) CloseAt(t time.Time) (Bar, bool) {
+	return Bar{}, false
+}
+
+func (b *thresholdBuilder) Current() (Bar, bool) {
+	return b.cur, b.has
+}
+
+func (b *thresholdBuilder) Flush() (Bar, bool) {
+	if b.has {
+		b.has = false
+		return b.cur, true
+	} else {
+		return Bar{}, false
+	}
+}
@@ -0,0 +1,137 @@
+/*
+- @Author: aztec
+- @Date: 2026-10-16 23:05:12
+- @Description: 实时行情源接口，以及本地websocket协议的消息格式
+- @
+- @Copyright (c) 2024 by aztec, All Rights Reserved.
+*/
+package feed
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/aztecqt/qbench/common"
+)
+
+const logPrefix = "feed"
+
+// 一条实时行情
+type Update struct {
+	InstId string
+	Time   time.Time // 行情时间。k线为收盘时间
+
+	// 行情数据。可以是以下类型：
+	// common.Ticker
+	// common.Depth
+	// common.Trade（包括强平成交，以Tag区分）
+	// common.FundingRate
+	// Kline
+	Data interface{}
+}
+
+// 已收盘的k线
+type Kline struct {
+	IntervalSec int              `json:"interval_sec"`
+	Unit        common.KlineUnit `json:"unit"` // Unit.Time为开盘时间
+}
+
+// 实时行情源
+// 对接不同的数据来源（交易所websocket、本地回放服务器等）只需要实现这个接口
+type Feed interface {
+	// 订阅指定品种的行情并开始接收
+	Start(instIds []string) bool
+
+	// 行情通道，行情源结束或出错时关闭
+	Updates() <-chan Update
+
+	// 停止接收
+	Close()
+}
+
+// 本地websocket协议的消息类型
+const (
+	MsgType_Ticker  = "ticker"
+	MsgType_Depth   = "depth"
+	MsgType_Trade   = "trade"
+	MsgType_Funding = "funding"
+	MsgType_Kline   = "kline"
+)
+
+// 本地websocket协议的行情消息
+type message struct {
+	Type   string          `json:"type"`
+	InstId string          `json:"inst_id"`
+	Ts     int64           `json:"ts"` // 毫秒时间戳
+	Data   json.RawMessage `json:"data"`
+}
+
+// 本地websocket协议的订阅请求
+type subscribeRequest struct {
+	InstIds []string `json:"inst_ids"`
+}
+
+// 把行情编码为消息
+func encodeUpdate(u Update) ([]byte, bool) {
+	msg := message{InstId: u.InstId, Ts: u.Time.UnixMilli()}
+	switch u.Data.(type) {
+	case common.Ticker:
+		msg.Type = MsgType_Ticker
+	case common.Depth:
+		msg.Type = MsgType_Depth
+	case common.Trade:
+		msg.Type = MsgType_Trade
+	case common.FundingRate:
+		msg.Type = MsgType_Funding
+	case Kline:
+		msg.Type = MsgType_Kline
+	default:
+		return nil, false
+	}
+
+	if b, err := json.Marshal(u.Data); err == nil {
+		msg.Data = b
+	} else {
+		return nil, false
+	}
+
+	if b, err := json.Marshal(msg); err == nil {
+		return b, true
+	} else {
+		return nil, false
+	}
+}
+
+// 从消息解码行情
+func decodeUpdate(b []byte) (Update, bool) {
+	msg := message{}
+	if err := json.Unmarshal(b, &msg); err != nil {
+		return Update{}, false
+	}
+
+	u := Update{InstId: msg.InstId, Time: time.UnixMilli(msg.Ts)}
+	ok := false
+	switch msg.Type {
+	case MsgType_Ticker:
+		u.Data, ok = decodeData[common.Ticker](msg.Data)
+	case MsgType_Depth:
+		u.Data, ok = decodeData[common.Depth](msg.Data)
+	case MsgType_Trade:
+		u.Data, ok = decodeData[common.Trade](msg.Data)
+	case MsgType_Funding:
+		u.Data, ok = decodeData[common.FundingRate](msg.Data)
+	case MsgType_Kline:
+		u.Data, ok = decodeData[Kline](msg.Data)
+	}
+
+	return u, ok
+}
+
+func decodeData[T any](b []byte) (interface{}, bool) {
+	var v T
+	if err := json.Unmarshal(b, &v); err == nil {
+		return v, true
+	} else {
+		return nil, false
+	}
+}
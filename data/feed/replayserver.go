@@ -0,0 +1,91 @@
+/*
+- @Author: aztec
+- @Date: 2026-10-16 23:32:27
+- @Description: 本地websocket回放服务器。按时间顺序把一段历史行情推送给连接者，用于测试实时执行器
+- @
+- @Copyright (c) 2024 by aztec, All Rights Reserved.
+*/
+package feed
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/aztecqt/qbench/common"
+	"github.com/gorilla/websocket"
+)
+
+type ReplayServer struct {
+	updates  []Update
+	speed    float64
+	upgrader websocket.Upgrader
+	server   *http.Server
+}
+
+// updates需要按时间排序
+// speed为回放倍速，按行情时间间隔/speed推送；小于等于0表示不等待，尽快推送
+func NewReplayServer(updates []Update, speed float64) *ReplayServer {
+	return &ReplayServer{updates: updates, speed: speed}
+}
+
+// 在addr（如127.0.0.1:9001）上开始监听，每个连接独立地从头回放
+func (r *ReplayServer) Start(addr string) bool {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		common.LogError(logPrefix, "listen on %s failed: %s", addr, err.Error())
+		return false
+	}
+
+	r.server = &http.Server{Handler: http.HandlerFunc(r.serve)}
+	go r.server.Serve(l)
+	common.LogNormal(logPrefix, "replay server listening on %s", addr)
+	return true
+}
+
+func (r *ReplayServer) Close() {
+	if r.server != nil {
+		r.server.Close()
+	}
+}
+
+func (r *ReplayServer) serve(w http.ResponseWriter, req *http.Request) {
+	conn, err := r.upgrader.Upgrade(w, req, nil)
+	if err != nil {
+		common.LogError(logPrefix, "upgrade failed: %s", err.Error())
+		return
+	}
+	defer conn.Close()
+
+	// 第一条消息为订阅请求
+	sub := subscribeRequest{}
+	if _, b, err := conn.ReadMessage(); err != nil || json.Unmarshal(b, &sub) != nil {
+		common.LogError(logPrefix, "invalid subscribe request from %s", req.RemoteAddr)
+		return
+	}
+
+	var prev time.Time
+	for _, u := range r.updates {
+		if !slices.Contains(sub.InstIds, u.InstId) {
+			continue
+		}
+
+		if r.speed > 0 && !prev.IsZero() {
+			if d := u.Time.Sub(prev); d > 0 {
+				time.Sleep(time.Duration(float64(d) / r.speed))
+			}
+		}
+		prev = u.Time
+
+		if b, ok := encodeUpdate(u); ok {
+			if err := conn.WriteMessage(websocket.TextMessage, b); err != nil {
+				return
+			}
+		}
+	}
+
+	// 回放结束
+	conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "replay finished"))
+}
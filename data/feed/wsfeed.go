@@ -0,0 +1,89 @@
+/*
+- @Author: aztec
+- @Date: 2026-10-16 23:18:40
+- @Description: 基于本地websocket协议的行情源，可以连接ReplayServer，或者协议相同的行情转发服务
+- @
+- @Copyright (c) 2024 by aztec, All Rights Reserved.
+*/
+package feed
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/aztecqt/qbench/common"
+	"github.com/gorilla/websocket"
+)
+
+type WsFeed struct {
+	url     string
+	conn    *websocket.Conn
+	updates chan Update
+	closed  chan struct{}
+	once    sync.Once
+}
+
+func NewWsFeed(url string) *WsFeed {
+	return &WsFeed{url: url, updates: make(chan Update, 1024), closed: make(chan struct{})}
+}
+
+func (f *WsFeed) Start(instIds []string) bool {
+	conn, _, err := websocket.DefaultDialer.Dial(f.url, nil)
+	if err != nil {
+		common.LogError(logPrefix, "dial %s failed: %s", f.url, err.Error())
+		return false
+	}
+
+	b, _ := json.Marshal(subscribeRequest{InstIds: instIds})
+	if err := conn.WriteMessage(websocket.TextMessage, b); err != nil {
+		common.LogError(logPrefix, "subscribe to %s failed: %s", f.url, err.Error())
+		conn.Close()
+		return false
+	}
+
+	f.conn = conn
+	go f.readLoop()
+	common.LogNormal(logPrefix, "connected to %s", f.url)
+	return true
+}
+
+func (f *WsFeed) Updates() <-chan Update {
+	return f.updates
+}
+
+func (f *WsFeed) Close() {
+	f.once.Do(func() {
+		close(f.closed)
+		if f.conn != nil {
+			f.conn.Close()
+		}
+	})
+}
+
+// 接收并解码消息，连接断开时关闭行情通道
+func (f *WsFeed) readLoop() {
+	defer close(f.updates)
+	for {
+		_, b, err := f.conn.ReadMessage()
+		if err != nil {
+			select {
+			case <-f.closed:
+			default:
+				if !websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+					common.LogError(logPrefix, "read from %s failed: %s", f.url, err.Error())
+				}
+			}
+			return
+		}
+
+		if u, ok := decodeUpdate(b); ok {
+			select {
+			case f.updates <- u:
+			case <-f.closed:
+				return
+			}
+		} else {
+			common.LogError(logPrefix, "invalid message: %s", string(b))
+		}
+	}
+}
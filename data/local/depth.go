@@ -36,18 +36,7 @@ func LoadDepth(t0, t1 time.Time, ex common.ExName, instId string, fnprg func(i,
 	i := 0
 	n := int(dt1.Sub(dt0).Hours()/24) + 1
 	for d := dt0; d.Unix() <= dt1.Unix(); d = d.AddDate(0, 0, 1) {
-		path := fmt.Sprintf("%s/depth/%s/%s/%s.depth", LocalDataPath, ex, instId, d.Format(time.DateOnly))
-		if bf, err := LoadZipOrRawFile(path); err == nil {
-			util.DeserializeToObjects(
-				bf,
-				func() *common.Depth { return &common.Depth{} },
-				func(dp *common.Depth) bool {
-					if dp.Time.UnixMilli() >= t0.UnixMilli() && dp.Time.UnixMilli() <= t1.UnixMilli() {
-						depths = append(depths, *dp)
-					}
-					return dp.Time.UnixMilli() < t1.UnixMilli()
-				})
-		}
+		depths = append(depths, loadDepthOfDay(d, t0, t1, ex, instId)...)
 
 		i++
 		if fnprg != nil {
@@ -57,3 +46,29 @@ func LoadDepth(t0, t1 time.Time, ex common.ExName, instId string, fnprg func(i,
 
 	return depths
 }
+
+// 按天迭代深度
+func IterDepth(t0, t1 time.Time, ex common.ExName, instId string) *DayIterator[common.Depth] {
+	return newDayIterator(t0, t1, func(d time.Time) []common.Depth {
+		return loadDepthOfDay(d, t0, t1, ex, instId)
+	}, func(dp common.Depth) time.Time { return dp.Time })
+}
+
+// 加载某一天的深度，仅保留[t0, t1]范围内的数据
+func loadDepthOfDay(d, t0, t1 time.Time, ex common.ExName, instId string) []common.Depth {
+	depths := []common.Depth{}
+	path := fmt.Sprintf("%s/depth/%s/%s/%s.depth", LocalDataPath, ex, instId, d.Format(time.DateOnly))
+	if bf, err := LoadZipOrRawFile(path); err == nil {
+		util.DeserializeToObjects(
+			bf,
+			func() *common.Depth { return &common.Depth{} },
+			func(dp *common.Depth) bool {
+				if dp.Time.UnixMilli() >= t0.UnixMilli() && dp.Time.UnixMilli() <= t1.UnixMilli() {
+					depths = append(depths, *dp)
+				}
+				return dp.Time.UnixMilli() < t1.UnixMilli()
+			})
+	}
+
+	return depths
+}
@@ -0,0 +1,73 @@
+/*
+- @Author: aztec
+- @Date: 2026-10-16 11:05:52
+- @Description: 资金费率数据的加载
+- @
+- @Copyright (c) 2024 by aztec, All Rights Reserved.
+*/
+package local
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aztecqt/dagger/util"
+	"github.com/aztecqt/qbench/common"
+)
+
+// 查询本地资金费率的可用instId
+func GetValidFundingInstIds(ex common.ExName) []string {
+	dir := fmt.Sprintf("%s/funding/%s", LocalDataPath, ex)
+	return GetInstIdsOfDir(dir)
+}
+
+// 查询本地资金费率的时间范围
+func GetValidFundingTimeRange(ex common.ExName, instId string) (t0, t1 time.Time, ok bool) {
+	dir := fmt.Sprintf("%s/funding/%s/%s", LocalDataPath, ex, instId)
+	return GetTimeRangeOfDir(dir)
+}
+
+// 加载资金费率
+func LoadFundingRates(t0, t1 time.Time, ex common.ExName, instId string, fnprg func(i, n int)) []common.FundingRate {
+	dt0 := util.DateOfTime(t0)
+	dt1 := util.DateOfTime(t1)
+	rates := []common.FundingRate{}
+	i := 0
+	n := int(dt1.Sub(dt0).Hours()/24) + 1
+	for d := dt0; d.Unix() <= dt1.Unix(); d = d.AddDate(0, 0, 1) {
+		rates = append(rates, loadFundingRatesOfDay(d, t0, t1, ex, instId)...)
+
+		i++
+		if fnprg != nil {
+			fnprg(i, n)
+		}
+	}
+
+	return rates
+}
+
+// 按天迭代资金费率
+func IterFundingRates(t0, t1 time.Time, ex common.ExName, instId string) *DayIterator[common.FundingRate] {
+	return newDayIterator(t0, t1, func(d time.Time) []common.FundingRate {
+		return loadFundingRatesOfDay(d, t0, t1, ex, instId)
+	}, func(r common.FundingRate) time.Time { return r.Time })
+}
+
+// 加载某一天的资金费率，仅保留[t0, t1]范围内的数据
+func loadFundingRatesOfDay(d, t0, t1 time.Time, ex common.ExName, instId string) []common.FundingRate {
+	rates := []common.FundingRate{}
+	path := fmt.Sprintf("%s/funding/%s/%s/%s.funding", LocalDataPath, ex, instId, d.Format(time.DateOnly))
+	if bf, err := LoadZipOrRawFile(path); err == nil {
+		util.DeserializeToObjects(
+			bf,
+			func() *common.FundingRate { return &common.FundingRate{} },
+			func(fr *common.FundingRate) bool {
+				if fr.Time.UnixMilli() >= t0.UnixMilli() && fr.Time.UnixMilli() <= t1.UnixMilli() {
+					rates = append(rates, *fr)
+				}
+				return fr.Time.UnixMilli() < t1.UnixMilli()
+			})
+	}
+
+	return rates
+}
@@ -0,0 +1,39 @@
+/*
+- @Author: aztec
+- @Date: 2026-10-16 19:55:37
+- @Description: 交易品种规则的加载
+- @
+- @Copyright (c) 2024 by aztec, All Rights Reserved.
+*/
+package local
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/aztecqt/qbench/common"
+)
+
+// 加载某交易所的交易品种规则，返回instId->规则
+// 文件路径为 %LocalDataPath%/instruments/%ex%.json，内容为InstrumentRule数组
+// 文件不存在或格式错误时返回false
+func LoadInstrumentRules(ex common.ExName) (map[string]common.InstrumentRule, bool) {
+	path := fmt.Sprintf("%s/instruments/%s.json", LocalDataPath, ex)
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+
+	rules := []common.InstrumentRule{}
+	if err := json.Unmarshal(b, &rules); err != nil {
+		return nil, false
+	}
+
+	m := map[string]common.InstrumentRule{}
+	for _, r := range rules {
+		m[r.InstId] = r
+	}
+
+	return m, true
+}
@@ -0,0 +1,74 @@
+/*
+- @Author: aztec
+- @Date: 2026-10-16 14:02:19
+- @Description: 按天惰性读取本地数据的迭代器
+- @
+- @Copyright (c) 2024 by aztec, All Rights Reserved.
+*/
+package local
+
+import (
+	"slices"
+	"time"
+
+	"github.com/aztecqt/dagger/util"
+)
+
+// 按天读取的数据迭代器
+// 本地数据都是按天存储的，迭代器每次只把一天的文件加载到内存中，读完之后再加载下一天
+// 这样无论时间范围多长，内存占用都只跟单日数据量有关
+// 行情归并要求每个迭代器按时间有序输出，所以每天的数据加载后如果发现乱序，会按时间重新排序（稳定排序，同一时间保持文件顺序）
+type DayIterator[T any] struct {
+	day       time.Time               // 下一个要加载的日期
+	lastDay   time.Time               // 最后一个日期
+	fnLoadDay func(day time.Time) []T // 加载某一天的数据（已按时间范围过滤）
+	fnTime    func(v T) time.Time     // 取出数据的时间
+	buf       []T                     // 当前日期的数据
+	idx       int                     // buf中的读取位置
+	i, n      int                     // 已加载天数、总天数
+}
+
+func newDayIterator[T any](t0, t1 time.Time, fnLoadDay func(day time.Time) []T, fnTime func(v T) time.Time) *DayIterator[T] {
+	dt0 := util.DateOfTime(t0)
+	dt1 := util.DateOfTime(t1)
+	return &DayIterator[T]{
+		day:       dt0,
+		lastDay:   dt1,
+		fnLoadDay: fnLoadDay,
+		fnTime:    fnTime,
+		n:         int(dt1.Sub(dt0).Hours()/24) + 1}
+}
+
+// 读取下一条数据，读完时返回false
+func (it *DayIterator[T]) Next() (T, bool) {
+	for it.idx >= len(it.buf) {
+		if it.day.Unix() > it.lastDay.Unix() {
+			var zero T
+			it.buf = nil
+			return zero, false
+		}
+
+		it.buf = it.fnLoadDay(it.day)
+		it.sortBuf()
+		it.idx = 0
+		it.day = it.day.AddDate(0, 0, 1)
+		it.i++
+	}
+
+	v := it.buf[it.idx]
+	it.idx++
+	return v, true
+}
+
+// 确保当天数据按时间有序
+func (it *DayIterator[T]) sortBuf() {
+	cmp := func(a, b T) int { return it.fnTime(a).Compare(it.fnTime(b)) }
+	if !slices.IsSortedFunc(it.buf, cmp) {
+		slices.SortStableFunc(it.buf, cmp)
+	}
+}
+
+// 加载进度（已加载天数、总天数）
+func (it *DayIterator[T]) Progress() (i, n int) {
+	return it.i, it.n
+}
@@ -89,18 +89,7 @@ func LoadKLine(t0, t1 time.Time, ex common.ExName, instId string, interval int,
 		i := 0
 		n := int(dt1.Sub(dt0).Hours()/24) + 1
 		for d := dt0; d.Unix() <= dt1.Unix(); d = d.AddDate(0, 0, 1) {
-			path := fmt.Sprintf("%s/klines/%s/%s/%s/%s.kline", LocalDataPath, ex, bar, instId, d.Format(time.DateOnly))
-			if bf, err := LoadZipOrRawFile(path); err == nil {
-				util.DeserializeToObjects(
-					bf,
-					func() *common.KlineUnit { return &common.KlineUnit{} },
-					func(ku *common.KlineUnit) bool {
-						if ku.Time.Unix() >= t0.Unix() && ku.Time.Unix() <= t1.Unix() {
-							kline.Units = append(kline.Units, *ku)
-						}
-						return ku.Time.Unix() < t1.Unix()
-					})
-			}
+			kline.Units = append(kline.Units, loadKlineUnitsOfDay(d, t0, t1, ex, instId, bar)...)
 
 			i++
 			if fnprg != nil {
@@ -112,3 +101,33 @@ func LoadKLine(t0, t1 time.Time, ex common.ExName, instId string, interval int,
 		return nil
 	}
 }
+
+// 按天迭代k线
+func IterKlineUnits(t0, t1 time.Time, ex common.ExName, instId string, interval int) (*DayIterator[common.KlineUnit], bool) {
+	if bar, ok := common.Interval2Bar(interval); ok {
+		return newDayIterator(t0, t1, func(d time.Time) []common.KlineUnit {
+			return loadKlineUnitsOfDay(d, t0, t1, ex, instId, bar)
+		}, func(ku common.KlineUnit) time.Time { return ku.Time }), true
+	} else {
+		return nil, false
+	}
+}
+
+// 加载某一天的k线，仅保留[t0, t1]范围内的数据
+func loadKlineUnitsOfDay(d, t0, t1 time.Time, ex common.ExName, instId string, bar common.Bar) []common.KlineUnit {
+	units := []common.KlineUnit{}
+	path := fmt.Sprintf("%s/klines/%s/%s/%s/%s.kline", LocalDataPath, ex, bar, instId, d.Format(time.DateOnly))
+	if bf, err := LoadZipOrRawFile(path); err == nil {
+		util.DeserializeToObjects(
+			bf,
+			func() *common.KlineUnit { return &common.KlineUnit{} },
+			func(ku *common.KlineUnit) bool {
+				if ku.Time.Unix() >= t0.Unix() && ku.Time.Unix() <= t1.Unix() {
+					units = append(units, *ku)
+				}
+				return ku.Time.Unix() < t1.Unix()
+			})
+	}
+
+	return units
+}
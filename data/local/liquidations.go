@@ -23,18 +23,7 @@ func LoadLiquidation(t0, t1 time.Time, ex common.ExName, instId string, fnprg fu
 	i := 0
 	n := int(dt1.Sub(dt0).Hours()/24) + 1
 	for d := dt0; d.Unix() <= dt1.Unix(); d = d.AddDate(0, 0, 1) {
-		path := fmt.Sprintf("%s/liquidation/%s/%s/%s.trades", LocalDataPath, ex, instId, d.Format(time.DateOnly))
-		if bf, err := LoadZipOrRawFile(path); err == nil {
-			util.DeserializeToObjects(
-				bf,
-				func() *common.Trade { return &common.Trade{} },
-				func(dp *common.Trade) bool {
-					if dp.Time.UnixMilli() >= t0.UnixMilli() && dp.Time.UnixMilli() <= t1.UnixMilli() {
-						trades = append(trades, *dp)
-					}
-					return dp.Time.UnixMilli() < t1.UnixMilli()
-				})
-		}
+		trades = append(trades, loadLiquidationOfDay(d, t0, t1, ex, instId)...)
 
 		i++
 		if fnprg != nil {
@@ -44,3 +33,29 @@ func LoadLiquidation(t0, t1 time.Time, ex common.ExName, instId string, fnprg fu
 
 	return trades
 }
+
+// 按天迭代爆仓成交
+func IterLiquidation(t0, t1 time.Time, ex common.ExName, instId string) *DayIterator[common.Trade] {
+	return newDayIterator(t0, t1, func(d time.Time) []common.Trade {
+		return loadLiquidationOfDay(d, t0, t1, ex, instId)
+	}, func(t common.Trade) time.Time { return t.Time })
+}
+
+// 加载某一天的爆仓成交，仅保留[t0, t1]范围内的数据
+func loadLiquidationOfDay(d, t0, t1 time.Time, ex common.ExName, instId string) []common.Trade {
+	trades := []common.Trade{}
+	path := fmt.Sprintf("%s/liquidation/%s/%s/%s.trades", LocalDataPath, ex, instId, d.Format(time.DateOnly))
+	if bf, err := LoadZipOrRawFile(path); err == nil {
+		util.DeserializeToObjects(
+			bf,
+			func() *common.Trade { return &common.Trade{} },
+			func(dp *common.Trade) bool {
+				if dp.Time.UnixMilli() >= t0.UnixMilli() && dp.Time.UnixMilli() <= t1.UnixMilli() {
+					trades = append(trades, *dp)
+				}
+				return dp.Time.UnixMilli() < t1.UnixMilli()
+			})
+	}
+
+	return trades
+}
@@ -35,17 +35,8 @@ func LoadTickers(t0, t1 time.Time, ex common.ExName, instId string, fnprg func(i
 	i := 0
 	n := int(dt1.Sub(dt0).Hours()/24) + 1
 	for d := dt0; d.Unix() <= dt1.Unix(); d = d.AddDate(0, 0, 1) {
-		path := fmt.Sprintf("%s/tickers/%s/%s/%s.ticker", LocalDataPath, ex, instId, d.Format(time.DateOnly))
-		if bf, err := LoadZipOrRawFile(path); err == nil {
-			util.DeserializeToObjects(
-				bf,
-				func() *common.Ticker { return &common.Ticker{} },
-				func(tk *common.Ticker) bool {
-					if tk.TimeStamp >= t0.UnixMilli() && tk.TimeStamp <= t1.UnixMilli() {
-						tickers = append(tickers, *tk)
-					}
-					return tk.TimeStamp < t1.UnixMilli()
-				})
+		if tks, ok := loadTickersOfDay(d, t0, t1, ex, instId); ok {
+			tickers = append(tickers, tks...)
 
 			i++
 			if fnprg != nil {
@@ -56,3 +47,31 @@ func LoadTickers(t0, t1 time.Time, ex common.ExName, instId string, fnprg func(i
 
 	return tickers
 }
+
+// 按天迭代tickers
+func IterTickers(t0, t1 time.Time, ex common.ExName, instId string) *DayIterator[common.Ticker] {
+	return newDayIterator(t0, t1, func(d time.Time) []common.Ticker {
+		tks, _ := loadTickersOfDay(d, t0, t1, ex, instId)
+		return tks
+	}, func(t common.Ticker) time.Time { return t.Time })
+}
+
+// 加载某一天的tickers，仅保留[t0, t1]范围内的数据
+func loadTickersOfDay(d, t0, t1 time.Time, ex common.ExName, instId string) ([]common.Ticker, bool) {
+	tickers := []common.Ticker{}
+	path := fmt.Sprintf("%s/tickers/%s/%s/%s.ticker", LocalDataPath, ex, instId, d.Format(time.DateOnly))
+	if bf, err := LoadZipOrRawFile(path); err == nil {
+		util.DeserializeToObjects(
+			bf,
+			func() *common.Ticker { return &common.Ticker{} },
+			func(tk *common.Ticker) bool {
+				if tk.TimeStamp >= t0.UnixMilli() && tk.TimeStamp <= t1.UnixMilli() {
+					tickers = append(tickers, *tk)
+				}
+				return tk.TimeStamp < t1.UnixMilli()
+			})
+		return tickers, true
+	} else {
+		return tickers, false
+	}
+}
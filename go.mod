@@ -34,7 +34,7 @@ require (
 	github.com/gobwas/httphead v0.1.0 // indirect
 	github.com/gobwas/pool v0.2.1 // indirect
 	github.com/gobwas/ws v1.1.0 // indirect
-	github.com/gorilla/websocket v1.5.0 // indirect
+	github.com/gorilla/websocket v1.5.0
 	github.com/influxdata/influxdb v1.11.1
 	github.com/jedib0t/go-pretty v4.3.0+incompatible
 	github.com/josharian/intern v1.0.0 // indirect
@@ -58,5 +58,5 @@ require (
 	golang.org/x/text v0.11.0 // indirect
 	gonum.org/v1/gonum v0.14.0 // indirect
 	gopkg.in/ini.v1 v1.42.0 // indirect
-	gopkg.in/yaml.v2 v2.4.0 // indirect
+	gopkg.in/yaml.v2 v2.4.0
 )
@@ -0,0 +1,46 @@
+/*
+- @Author: aztec
+- @Date: 2026-10-17 14:05:31
+- @Description: 测试用的公共构造函数（数值、盘口、成交）
+- @
+- @Copyright (c) 2024 by aztec, All Rights Reserved.
+*/
+package testutil
+
+import (
+	"time"
+
+	"github.com/aztecqt/qbench/common"
+	"github.com/shopspring/decimal"
+)
+
+func Dec(v float64) decimal.Decimal {
+	return decimal.NewFromFloat(v)
+}
+
+// 构造盘口，每一档为[价格, 数量]，买盘价格从高到低，卖盘价格从低到高
+func Depth(t time.Time, bids, asks [][2]float64) common.Depth {
+	d := common.Depth{Time: t}
+	for _, l := range bids {
+		d.Bids = append(d.Bids, common.DepthUnit{Price: Dec(l[0]), Amount: Dec(l[1]), OrderCount: 1})
+	}
+	for _, l := range asks {
+		d.Asks = append(d.Asks, common.DepthUnit{Price: Dec(l[0]), Amount: Dec(l[1]), OrderCount: 1})
+	}
+
+	if len(d.Bids) > 0 {
+		d.Buy1 = d.Bids[0].Price
+	}
+	if len(d.Asks) > 0 {
+		d.Sell1 = d.Asks[0].Price
+	}
+	if len(d.Bids) > 0 && len(d.Asks) > 0 {
+		d.Mid = d.Buy1.Add(d.Sell1).Div(decimal.NewFromInt(2))
+	}
+	return d
+}
+
+// 构造一笔市场成交，side为主动方向（'b'/'s'）
+func Trade(t time.Time, price, size float64, side byte) common.Trade {
+	return common.Trade{Time: t, Price: Dec(price), Size: Dec(size), Side: side, Tag: common.TradeTagNormal}
+}